@@ -1,20 +1,179 @@
 package app
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
-	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"shakespearing-pokemon/api/caches/pokemon_cache"
+	"shakespearing-pokemon/api/caches/translation_cache"
+	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/controllers/health_controller"
+	"shakespearing-pokemon/api/controllers/translation_controller"
+	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
+	"shakespearing-pokemon/api/logger"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/middlewares"
+	"shakespearing-pokemon/api/providers/pokemon_provider"
+	"shakespearing-pokemon/api/providers/translation_provider"
+	"shakespearing-pokemon/api/services"
+	"syscall"
+	"time"
 )
 
-var (
-	router = gin.Default()
+const (
+	//ExitOK, ExitServerError and ExitUncleanShutdown are the exit codes of the process: the server was shut down
+	//gracefully, the server could not serve, e.g. its address is in use, or the server was shut down but in-flight
+	//requests were cut or the caches and logs could not be written to disk
+	ExitOK              = 0
+	ExitServerError     = 1
+	ExitUncleanShutdown = 2
 )
 
-//RunApp will run constantly until the application is shutdown
-func RunApp() {
-	routes()
+//App is an instance of the application, its own router, services, providers, http client and metrics registry, wired
+//from a configuration. Only the logger is shared by the instances of a process
+type App struct {
+	router           *gin.Engine
+	metrics          *metrics.Registry
+	translationCache *translation_cache.TranslationCache
+}
+
+//New wires an instance of the application from the configuration, an error is returned if the translation cache
+//cannot be loaded from disk or the configuration was not validated
+func New(cfg *config.Config) (*App, error) {
+	trustedProxies, err := middlewares.ParseNetworks(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
 
-	err := router.Run(":8080")
+	registry := metrics.NewRegistry()
+	client := restclient.New()
+	pokemonProvider := pokemon_provider.New(client, cfg.PokeAPI, registry)
+	registry.RegisterCircuitBreaker("pokeapi", pokemonProvider.CircuitBreakerState)
+	translationProvider := translation_provider.New(client, cfg.Translation, registry)
+	registry.RegisterCircuitBreaker("funtranslations", translationProvider.CircuitBreakerState)
+	registry.RegisterTranslationQuota(translationProvider.QuotaStatus)
+
+	translationCache, err := translation_cache.New(translation_cache.Config{
+		Path:       cfg.Cache.Translation.Path,
+		TTL:        cfg.Cache.Translation.TTL.Duration,
+		StaleTTL:   cfg.Cache.Translation.StaleTTL.Duration,
+		MaxEntries: cfg.Cache.Translation.MaxEntries,
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	registry.RegisterCache("translation", translationCache.Stats)
+	cachedPokemonProvider := pokemon_cache.NewCachedProvider(pokemonProvider, pokemon_cache.Config{
+		TTL:         cfg.Cache.Pokemon.TTL.Duration,
+		NegativeTTL: cfg.Cache.Pokemon.NegativeTTL.Duration,
+		MaxEntries:  cfg.Cache.Pokemon.MaxEntries,
+	})
+	registry.RegisterCache("pokemon", cachedPokemonProvider.Stats)
+
+	translationService := services.NewTranslationService(cachedPokemonProvider,
+		services.NewTranslationChain(cfg.Translation.Fallback, translation_cache.NewCachedProvider(translationProvider, translationCache),
+			translation_cache.NewStaleProvider(translationCache)))
+	healthService := services.NewHealthService(client,
+		services.Dependency{Name: "pokeapi", Url: pokemonProvider.BaseUrl, BreakerState: pokemonProvider.CircuitBreakerState},
+		services.Dependency{Name: "funtranslations", Url: translationProvider.BaseUrl, BreakerState: translationProvider.CircuitBreakerState,
+			Optional: fallsBack(cfg.Translation.Fallback)},
+	)
+
+	app := &App{router: gin.New(), metrics: registry, translationCache: translationCache}
+	app.routes(cfg, trustedProxies,
+		health_controller.New(healthService),
+		translation_controller.New(translationService, services.NewBatchService(translationService, cfg.Batch)),
+	)
+	return app, nil
+}
+
+//Handler returns the handler serving the routes of the application
+func (a *App) Handler() http.Handler {
+	return a.router
+}
+
+//Flush writes the translation cache to disk
+func (a *App) Flush() error {
+	return a.translationCache.Flush()
+}
+
+//RunApp will run constantly until the application receives SIGINT or SIGTERM, then it drains the in-flight requests and
+//returns the exit code of the process
+func RunApp(cfg *config.Config) int {
+	level, _ := logger.ParseLevel(cfg.Log.Level)
+	logger.SetLevel(level)
+	if cfg.Log.Path != "" {
+		file, err := os.OpenFile(cfg.Log.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Error("error when opening the log file", logger.Fields{"error": err})
+			return ExitServerError
+		}
+		defer file.Close()
+		logger.SetOutput(file)
 	}
+
+	gin.SetMode(cfg.Server.Mode)
+	app, err := New(cfg)
+	if err != nil {
+		logger.Error("error when building the application", logger.Fields{"error": err})
+		return ExitServerError
+	}
+
+	listener, err := net.Listen("tcp", cfg.Server.Address)
+	if err != nil {
+		logger.Error("error when running the server", logger.Fields{"error": err})
+		return ExitServerError
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	logger.Info("starting server", logger.Fields{"address": listener.Addr().String()})
+	return serve(&http.Server{Handler: app.Handler()}, listener, signals, cfg.Server.ShutdownTimeout.Duration, app.Flush)
+}
+
+//serve serves the requests until the server fails or a signal is received, then it stops accepting connections, waits
+//up to gracePeriod for the in-flight requests to complete and flushes the caches and logs to disk
+func serve(server *http.Server, listener net.Listener, signals <-chan os.Signal, gracePeriod time.Duration, flush func() error) int {
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.Serve(listener)
+	}()
+
+	code := ExitOK
+	select {
+	case err := <-failed:
+		logger.Error("error when running the server", logger.Fields{"error": err})
+		code = ExitServerError
+	case received := <-signals:
+		logger.Info("shutting down the server", logger.Fields{"signal": received.String(), "grace_period": gracePeriod.String()})
+		ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			//the requests still in flight are cut, their clients get a connection error
+			logger.Error("error when draining the in-flight requests", logger.Fields{"error": err})
+			server.Close()
+			code = ExitUncleanShutdown
+		}
+	}
+
+	if err := flush(); err != nil {
+		logger.Error("error when flushing the translation cache", logger.Fields{"error": err})
+		code = ExitUncleanShutdown
+	}
+	logger.Info("server stopped")
+	if err := logger.Sync(); err != nil {
+		code = ExitUncleanShutdown
+	}
+	return code
+}
+
+//fallsBack tells whether the translations can do without the FunTranslations API, i.e. it is not the only engine
+func fallsBack(fallback []string) bool {
+	return len(fallback) != 1 || fallback[0] != shksprean_pokemon_domain.EngineFunTranslations
 }
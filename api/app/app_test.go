@@ -0,0 +1,155 @@
+package app
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"shakespearing-pokemon/api/config"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//startServing serves a handler blocking until released, it returns the address served and the channel receiving the
+//exit code
+func startServing(t *testing.T, gracePeriod time.Duration, flush func() error) (string, chan<- os.Signal, chan<- struct{}, <-chan struct{}, <-chan int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("Spits fire."))
+	})
+
+	signals := make(chan os.Signal, 1)
+	exitCode := make(chan int, 1)
+	go func() {
+		exitCode <- serve(&http.Server{Handler: handler}, listener, signals, gracePeriod, flush)
+	}()
+	return "http://" + listener.Addr().String(), signals, release, started, exitCode
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	flushed := false
+	url, signals, release, started, exitCode := startServing(t, 5*time.Second, func() error {
+		flushed = true
+		return nil
+	})
+
+	responses := make(chan string, 1)
+	go func() {
+		response, err := http.Get(url)
+		assert.Nil(t, err)
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		responses <- string(body)
+	}()
+	<-started
+
+	signals <- syscall.SIGTERM
+	//new connections are refused while the in-flight request is drained
+	time.Sleep(50 * time.Millisecond)
+	_, err := http.Get(url)
+	assert.NotNil(t, err)
+
+	close(release)
+	assert.EqualValues(t, "Spits fire.", <-responses)
+	assert.EqualValues(t, ExitOK, <-exitCode)
+	assert.True(t, flushed)
+}
+
+func TestServeGracePeriodElapsed(t *testing.T) {
+	url, signals, release, started, exitCode := startServing(t, 50*time.Millisecond, func() error { return nil })
+	defer close(release)
+
+	go http.Get(url)
+	<-started
+
+	signals <- syscall.SIGINT
+	assert.EqualValues(t, ExitUncleanShutdown, <-exitCode)
+}
+
+func TestServeFlushError(t *testing.T) {
+	_, signals, _, _, exitCode := startServing(t, time.Second, func() error { return errors.New("disk full") })
+
+	signals <- syscall.SIGTERM
+	assert.EqualValues(t, ExitUncleanShutdown, <-exitCode)
+}
+
+//newTestConfig configures an instance of the application calling the given PokeAPI and FunTranslations servers, its
+//translation cache is persisted in a temporary directory
+func newTestConfig(t *testing.T, pokeApi *httptest.Server, funTranslations *httptest.Server) *config.Config {
+	dir, err := ioutil.TempDir("", "app")
+	assert.Nil(t, err)
+	cfg := config.Default()
+	cfg.PokeAPI.BaseUrl = pokeApi.URL
+	cfg.Translation.BaseUrl = funTranslations.URL
+	cfg.Cache.Translation.Path = filepath.Join(dir, "translation_cache.json")
+	return cfg
+}
+
+func TestNewBuildsIndependentInstances(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	readyConfig := newTestConfig(t, up, up)
+	defer os.RemoveAll(filepath.Dir(readyConfig.Cache.Translation.Path))
+	notReadyConfig := newTestConfig(t, down, up)
+	defer os.RemoveAll(filepath.Dir(notReadyConfig.Cache.Translation.Path))
+
+	ready, err := New(readyConfig)
+	assert.Nil(t, err)
+	notReady, err := New(notReadyConfig)
+	assert.Nil(t, err)
+
+	//each instance probes the apis it was configured with
+	for instance, expectedStatus := range map[*App]int{ready: http.StatusOK, notReady: http.StatusServiceUnavailable} {
+		response := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		instance.Handler().ServeHTTP(response, request)
+		assert.EqualValues(t, expectedStatus, response.Code)
+	}
+
+	//each instance exposes its own metrics
+	for instance, expectedStatus := range map[*App]string{ready: "200", notReady: "503"} {
+		response := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+		instance.Handler().ServeHTTP(response, request)
+		body := response.Body.String()
+		assert.Contains(t, body, `http_requests_total{method="GET",route="/readyz",status="`+expectedStatus+`"} 1`)
+		assert.EqualValues(t, 1, strings.Count(body, "http_requests_total{"))
+		assert.EqualValues(t, 1, strings.Count(body, `circuit_breaker_state{upstream="pokeapi"}`))
+		assert.EqualValues(t, 1, strings.Count(body, `cache_entries{cache="translation"}`))
+	}
+}
+
+func TestNewInvalidTranslationCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	cfg := newTestConfig(t, server, server)
+	defer os.RemoveAll(filepath.Dir(cfg.Cache.Translation.Path))
+	assert.Nil(t, ioutil.WriteFile(cfg.Cache.Translation.Path, []byte("{"), 0644))
+
+	app, err := New(cfg)
+	assert.Nil(t, app)
+	assert.NotNil(t, err)
+}
+
+func TestFallsBack(t *testing.T) {
+	assert.True(t, fallsBack([]string{"funtranslations", "cache", "local", "original"}))
+	assert.True(t, fallsBack([]string{"local"}))
+	assert.False(t, fallsBack([]string{"funtranslations"}))
+}
@@ -1,7 +1,32 @@
 package app
 
-import "shakespearing-pokemon/api/controllers/translation_controller"
+import (
+	"github.com/gin-gonic/gin"
+	"net"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/controllers/health_controller"
+	"shakespearing-pokemon/api/controllers/translation_controller"
+	"shakespearing-pokemon/api/middlewares"
+	"shakespearing-pokemon/api/utils/ratelimit"
+)
 
-func routes() {
-	router.GET("/pokemon/:pokemonName", translation_controller.HandleShakespeareanPokemonTranslationRequest)
+func (a *App) routes(cfg *config.Config, trustedProxies []*net.IPNet, healthController *health_controller.Controller, translationController *translation_controller.Controller) {
+	a.router.Use(gin.Recovery(), middlewares.RequestID(), middlewares.Logger(), middlewares.Metrics(a.metrics))
+
+	a.router.GET("/healthz", healthController.HandleLivenessRequest)
+	a.router.GET("/readyz", healthController.HandleReadinessRequest)
+	a.router.GET("/metrics", gin.WrapH(a.metrics.Handler()))
+	//only the routes calling the external apis are rate limited, probes and scrapes must keep working
+	rateLimit := middlewares.RateLimit(middlewares.RateLimitConfig{
+		Config: ratelimit.Config{
+			Limit:  cfg.RateLimit.Limit,
+			Window: cfg.RateLimit.Window.Duration,
+		},
+		MaxClients:     cfg.RateLimit.MaxClients,
+		APIKeys:        cfg.RateLimit.APIKeys,
+		TrustedProxies: trustedProxies,
+	})
+	a.router.GET("/pokemon/:pokemonName", rateLimit, translationController.HandleShakespeareanPokemonTranslationRequest)
+	a.router.GET("/pokemon/:pokemonName/:style", rateLimit, translationController.HandleShakespeareanPokemonTranslationRequest)
+	a.router.POST("/pokemon/batch", rateLimit, translationController.HandleShakespeareanPokemonBatchRequest)
 }
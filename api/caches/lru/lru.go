@@ -0,0 +1,194 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+//Cache is a concurrency safe, size bounded, least recently used cache whose entries expire after a time to live.
+//A maxEntries lower or equal than zero means the cache is unbounded, a ttl lower or equal than zero means entries
+//never expire
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	staleTTL   time.Duration
+	ll         *list.List
+	items      map[string]*list.Element
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+	now        func() time.Time
+}
+
+//Entry is a snapshot of a single cached value
+type Entry struct {
+	Key       string
+	Value     interface{}
+	ExpiresAt time.Time
+}
+
+//Stats holds the counters of the cache since its creation
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+func New(maxEntries int, ttl time.Duration) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+//SetStaleTTL keeps the expired entries for staleTTL more, so that they can still be read with GetStale
+func (c *Cache) SetStaleTTL(staleTTL time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.staleTTL = staleTTL
+}
+
+//Get returns the value stored under key, expired entries are reported as a miss and removed once they are too old to
+//be read with GetStale
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*Entry)
+	if c.expired(entry) {
+		if c.gone(entry) {
+			c.removeElement(element)
+		}
+		c.misses++
+		return nil, false
+	}
+
+	c.ll.MoveToFront(element)
+	c.hits++
+	return entry.Value, true
+}
+
+//GetStale returns the value stored under key even if it expired less than the stale ttl ago, it neither counts as a
+//hit or a miss nor makes the entry more recently used
+func (c *Cache) GetStale(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*Entry)
+	if c.gone(entry) {
+		c.removeElement(element)
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+//Set stores value under key using the default ttl of the cache
+func (c *Cache) Set(key string, value interface{}) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+//SetWithTTL stores value under key, overriding the default ttl of the cache
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+	c.SetWithExpiry(key, value, expiresAt)
+}
+
+//SetWithExpiry stores value under key until expiresAt, a zero expiresAt means the entry never expires
+func (c *Cache) SetWithExpiry(key string, value interface{}, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*Entry)
+		entry.Value = value
+		entry.ExpiresAt = expiresAt
+		c.ll.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&Entry{Key: key, Value: value, ExpiresAt: expiresAt})
+
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+		c.evictions++
+	}
+}
+
+//Remove deletes key from the cache if present
+func (c *Cache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Entries:   c.ll.Len(),
+	}
+}
+
+//Entries returns the entries which are not expired, ordered from the least to the most recently used
+func (c *Cache) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]Entry, 0, c.ll.Len())
+	for element := c.ll.Back(); element != nil; element = element.Prev() {
+		entry := element.Value.(*Entry)
+		if !c.expired(entry) {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+func (c *Cache) expired(entry *Entry) bool {
+	return !entry.ExpiresAt.IsZero() && !c.now().Before(entry.ExpiresAt)
+}
+
+//gone reports whether the entry expired more than the stale ttl ago
+func (c *Cache) gone(entry *Entry) bool {
+	return !entry.ExpiresAt.IsZero() && !c.now().Before(entry.ExpiresAt.Add(c.staleTTL))
+}
+
+func (c *Cache) removeElement(element *list.Element) {
+	c.ll.Remove(element)
+	delete(c.items, element.Value.(*Entry).Key)
+}
@@ -0,0 +1,105 @@
+package lru
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGetAndSet(t *testing.T) {
+	cache := New(2, 0)
+
+	value, ok := cache.Get("charizard")
+	assert.False(t, ok)
+	assert.Nil(t, value)
+
+	cache.Set("charizard", "Spits fire that is hot enough to melt boulders.")
+	value, ok = cache.Get("charizard")
+	assert.True(t, ok)
+	assert.EqualValues(t, "Spits fire that is hot enough to melt boulders.", value)
+
+	stats := cache.Stats()
+	assert.EqualValues(t, 1, stats.Hits)
+	assert.EqualValues(t, 1, stats.Misses)
+	assert.EqualValues(t, 1, stats.Entries)
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := New(2, 0)
+
+	cache.Set("bulbasaur", 1)
+	cache.Set("charmander", 4)
+	//using bulbasaur makes charmander the least recently used entry
+	_, _ = cache.Get("bulbasaur")
+	cache.Set("squirtle", 7)
+
+	_, ok := cache.Get("charmander")
+	assert.False(t, ok)
+	_, ok = cache.Get("bulbasaur")
+	assert.True(t, ok)
+	_, ok = cache.Get("squirtle")
+	assert.True(t, ok)
+	assert.EqualValues(t, 1, cache.Stats().Evictions)
+	assert.EqualValues(t, 2, cache.Len())
+}
+
+func TestEntriesExpire(t *testing.T) {
+	now := time.Date(2020, time.October, 10, 12, 0, 0, 0, time.UTC)
+	cache := New(0, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("pikachu", 25)
+	cache.SetWithTTL("raichu", 26, time.Hour)
+	cache.SetWithExpiry("mew", 151, time.Time{})
+
+	now = now.Add(2 * time.Minute)
+
+	_, ok := cache.Get("pikachu")
+	assert.False(t, ok)
+	_, ok = cache.Get("raichu")
+	assert.True(t, ok)
+	_, ok = cache.Get("mew")
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, cache.Len())
+}
+
+func TestGetStale(t *testing.T) {
+	now := time.Date(2020, time.October, 10, 12, 0, 0, 0, time.UTC)
+	cache := New(0, time.Minute)
+	cache.now = func() time.Time { return now }
+	cache.SetStaleTTL(time.Hour)
+
+	cache.Set("pikachu", 25)
+	now = now.Add(2 * time.Minute)
+
+	_, ok := cache.Get("pikachu")
+	assert.False(t, ok)
+	//the expired entry is kept for the stale ttl
+	value, ok := cache.GetStale("pikachu")
+	assert.True(t, ok)
+	assert.EqualValues(t, 25, value)
+	assert.EqualValues(t, 1, cache.Stats().Misses)
+
+	now = now.Add(time.Hour)
+	_, ok = cache.GetStale("pikachu")
+	assert.False(t, ok)
+	assert.EqualValues(t, 0, cache.Len())
+}
+
+func TestEntries(t *testing.T) {
+	now := time.Date(2020, time.October, 10, 12, 0, 0, 0, time.UTC)
+	cache := New(0, 0)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("bulbasaur", 1)
+	cache.SetWithTTL("ivysaur", 2, time.Second)
+	cache.Set("venusaur", 3)
+	cache.Remove("venusaur")
+
+	now = now.Add(time.Minute)
+
+	entries := cache.Entries()
+	assert.EqualValues(t, 1, len(entries))
+	assert.EqualValues(t, "bulbasaur", entries[0].Key)
+	assert.EqualValues(t, 1, entries[0].Value)
+}
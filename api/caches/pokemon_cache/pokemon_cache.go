@@ -0,0 +1,66 @@
+package pokemon_cache
+
+import (
+	"context"
+	"shakespearing-pokemon/api/caches/lru"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/pokemon/pokemon_domain"
+	"time"
+)
+
+//Config defines how long species information and not found results are kept and how many species are kept in memory
+type Config struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MaxEntries  int
+}
+
+type pokemonProviderInterface interface {
+	GetPokemonInfo(ctx context.Context, request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error)
+}
+
+//CachedProvider keeps the species information in memory and only calls the wrapped provider on a miss,
+//"pokemon not found" errors are cached as well for a shorter time
+type CachedProvider struct {
+	provider    pokemonProviderInterface
+	entries     *lru.Cache
+	negativeTTL time.Duration
+}
+
+//cacheEntry holds either the species information or the not found error returned by the wrapped provider
+type cacheEntry struct {
+	response      *pokemon_domain.PokemonInfoResponse
+	errorResponse *api_error.Error
+}
+
+func NewCachedProvider(provider pokemonProviderInterface, config Config) *CachedProvider {
+	return &CachedProvider{
+		provider:    provider,
+		entries:     lru.New(config.MaxEntries, config.TTL),
+		negativeTTL: config.NegativeTTL,
+	}
+}
+
+func (p *CachedProvider) GetPokemonInfo(ctx context.Context, request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+	key := request.Name
+	if value, ok := p.entries.Get(key); ok {
+		entry := value.(cacheEntry)
+		return entry.response, entry.errorResponse
+	}
+
+	response, errorResponse := p.provider.GetPokemonInfo(ctx, request)
+	if errorResponse != nil {
+		if errorResponse.Code() == api_error.CodePokemonNotFound && p.negativeTTL > 0 {
+			p.entries.SetWithTTL(key, cacheEntry{errorResponse: errorResponse}, p.negativeTTL)
+		}
+		return nil, errorResponse
+	}
+
+	p.entries.Set(key, cacheEntry{response: response})
+	return response, nil
+}
+
+//Stats returns the hit, miss and eviction counters of the cache
+func (p *CachedProvider) Stats() lru.Stats {
+	return p.entries.Stats()
+}
@@ -0,0 +1,88 @@
+package pokemon_cache
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/pokemon/pokemon_domain"
+	"testing"
+	"time"
+)
+
+type getPokemonProviderMock struct {
+	getPokemonInfo func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error)
+}
+
+func (p *getPokemonProviderMock) GetPokemonInfo(ctx context.Context, request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+	return p.getPokemonInfo(request)
+}
+
+func TestGetPokemonInfoIsCached(t *testing.T) {
+	calls := 0
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		calls++
+		return &pokemon_domain.PokemonInfoResponse{Name: "charizard"}, nil
+	}
+
+	provider := NewCachedProvider(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, Config{TTL: time.Hour, NegativeTTL: time.Minute, MaxEntries: 10})
+	for _, name := range []string{"charizard", "charizard", "charizard"} {
+		response, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: name})
+		assert.Nil(t, errorResponse)
+		assert.EqualValues(t, "charizard", response.Name)
+	}
+
+	assert.EqualValues(t, 1, calls)
+	assert.EqualValues(t, 2, provider.Stats().Hits)
+}
+
+func TestGetPokemonInfoNotFoundIsCached(t *testing.T) {
+	calls := 0
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		calls++
+		return nil, api_error.New(http.StatusNotFound, api_error.CodePokemonNotFound, "pokemon not found")
+	}
+
+	provider := NewCachedProvider(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, Config{TTL: time.Hour, NegativeTTL: time.Minute, MaxEntries: 10})
+	for i := 0; i < 2; i++ {
+		response, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "missingno"})
+		assert.Nil(t, response)
+		assert.EqualValues(t, http.StatusNotFound, errorResponse.Status())
+		assert.EqualValues(t, "pokemon not found", errorResponse.Message())
+	}
+
+	assert.EqualValues(t, 1, calls)
+}
+
+func TestGetPokemonInfoOtherErrorsAreNotCached(t *testing.T) {
+	calls := 0
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		calls++
+		return nil, api_error.New(http.StatusInternalServerError, api_error.CodeUpstreamError, "error from external api")
+	}
+
+	provider := NewCachedProvider(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, Config{TTL: time.Hour, NegativeTTL: time.Minute, MaxEntries: 10})
+	for i := 0; i < 2; i++ {
+		response, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+		assert.Nil(t, response)
+		assert.EqualValues(t, http.StatusInternalServerError, errorResponse.Status())
+	}
+
+	assert.EqualValues(t, 2, calls)
+	assert.EqualValues(t, 0, provider.Stats().Entries)
+}
+
+func TestGetPokemonInfoCacheIsBounded(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{Name: request.Name}, nil
+	}
+
+	provider := NewCachedProvider(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, Config{TTL: time.Hour, NegativeTTL: time.Minute, MaxEntries: 2})
+	for _, name := range []string{"bulbasaur", "charmander", "squirtle"} {
+		_, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: name})
+		assert.Nil(t, errorResponse)
+	}
+
+	assert.EqualValues(t, 2, provider.Stats().Entries)
+	assert.EqualValues(t, 1, provider.Stats().Evictions)
+}
@@ -0,0 +1,60 @@
+package translation_cache
+
+import (
+	"context"
+	"net/http"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/translation/translation_domain"
+	"shakespearing-pokemon/api/logger"
+)
+
+type translationProviderInterface interface {
+	Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error)
+}
+
+//CachedProvider serves translations from the cache and only calls the wrapped provider on a miss
+type CachedProvider struct {
+	provider translationProviderInterface
+	cache    *TranslationCache
+}
+
+func NewCachedProvider(provider translationProviderInterface, cache *TranslationCache) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		cache:    cache,
+	}
+}
+
+func (p *CachedProvider) Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+	if response, ok := p.cache.Get(request.Style, request.Text); ok {
+		return response, nil
+	}
+
+	response, errorResponse := p.provider.Translate(ctx, request)
+	if errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	//a translation which could not be persisted is still valid, hence the error is only logged
+	if err := p.cache.Set(request.Style, request.Text, *response); err != nil {
+		logger.FromContext(ctx).Error("error when trying to persist the translation cache", logger.Fields{"error": err})
+	}
+	return response, nil
+}
+
+//StaleProvider serves the cached translations, even the expired ones, without calling the wrapped provider. It is used
+//when no fresh translation can be had
+type StaleProvider struct {
+	cache *TranslationCache
+}
+
+func NewStaleProvider(cache *TranslationCache) *StaleProvider {
+	return &StaleProvider{cache: cache}
+}
+
+func (p *StaleProvider) Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+	if response, ok := p.cache.GetStale(request.Style, request.Text); ok {
+		return response, nil
+	}
+	return nil, api_error.New(http.StatusNotFound, api_error.CodeTranslationUnavailable, "no cached translation")
+}
@@ -0,0 +1,194 @@
+package translation_cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"shakespearing-pokemon/api/caches/lru"
+	"shakespearing-pokemon/api/domains/translation/translation_domain"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Config defines where the cache is persisted, how long translations are kept and how many of them are kept. Expired
+//translations can still be served as a last resort for StaleTTL more, until the process restarts
+type Config struct {
+	Path       string
+	TTL        time.Duration
+	StaleTTL   time.Duration
+	MaxEntries int
+}
+
+//TranslationCache stores translation responses keyed by their style and normalized source text, every change is
+//written through to a json file so that the translations survive restarts
+type TranslationCache struct {
+	entries *lru.Cache
+	path    string
+	fileMu  sync.Mutex
+}
+
+//Used to persist the cache entries on disk in the form of:
+//	{
+//		"entries": [
+//			{
+//				"key": "CHARIZARD flies around the sky in search of powerful opponents.",
+//				"style": "shakespeare",
+//				"value": {
+//					"contents": {
+//						"translated": "Charizard flies 'round the sky in search of powerful opponents."
+//					}
+//				},
+//				"expires_at": "2020-11-10T12:00:00Z"
+//			}
+//		]
+//	}
+type cacheFile struct {
+	Entries []cacheFileEntry `json:"entries"`
+}
+
+type cacheFileEntry struct {
+	Key       string                                 `json:"key"`
+	Style     string                                 `json:"style,omitempty"`
+	Value     translation_domain.TranslationResponse `json:"value"`
+	ExpiresAt time.Time                              `json:"expires_at"`
+}
+
+//New creates a translation cache, restoring the entries previously persisted in config.Path if any
+func New(config Config) (*TranslationCache, error) {
+	cache := &TranslationCache{
+		entries: lru.New(config.MaxEntries, config.TTL),
+		path:    config.Path,
+	}
+	cache.entries.SetStaleTTL(config.StaleTTL)
+
+	if err := cache.load(); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+//NormalizeKey collapses whitespaces so that the same text formatted differently is translated only once
+func NormalizeKey(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+//cacheKey prefixes the normalized text with its style, the separator cannot be part of a normalized text
+func cacheKey(style string, text string) string {
+	if style == "" {
+		style = translation_domain.DefaultStyle
+	}
+	return style + "\n" + NormalizeKey(text)
+}
+
+func splitCacheKey(key string) (style string, text string) {
+	parts := strings.SplitN(key, "\n", 2)
+	return parts[0], parts[1]
+}
+
+func (c *TranslationCache) Get(style string, text string) (*translation_domain.TranslationResponse, bool) {
+	value, ok := c.entries.Get(cacheKey(style, text))
+	if !ok {
+		return nil, false
+	}
+	response := value.(translation_domain.TranslationResponse)
+	return &response, true
+}
+
+//GetStale returns the cached translation even if it expired, as long as it is younger than the stale ttl
+func (c *TranslationCache) GetStale(style string, text string) (*translation_domain.TranslationResponse, bool) {
+	value, ok := c.entries.GetStale(cacheKey(style, text))
+	if !ok {
+		return nil, false
+	}
+	response := value.(translation_domain.TranslationResponse)
+	return &response, true
+}
+
+//Set caches the response and writes the whole cache to disk
+func (c *TranslationCache) Set(style string, text string, response translation_domain.TranslationResponse) error {
+	c.entries.Set(cacheKey(style, text), response)
+	return c.Flush()
+}
+
+//Stats returns the hit, miss and eviction counters of the cache
+func (c *TranslationCache) Stats() lru.Stats {
+	return c.entries.Stats()
+}
+
+//Flush writes the entries which are not expired to disk, the file is replaced atomically
+func (c *TranslationCache) Flush() error {
+	if c.path == "" {
+		return nil
+	}
+
+	//the snapshot is taken under the file lock, otherwise an older snapshot could be written after a newer one
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+
+	entries := c.entries.Entries()
+	file := cacheFile{Entries: make([]cacheFileEntry, 0, len(entries))}
+	for _, entry := range entries {
+		style, text := splitCacheKey(entry.Key)
+		file.Entries = append(file.Entries, cacheFileEntry{
+			Key:       text,
+			Style:     style,
+			Value:     entry.Value.(translation_domain.TranslationResponse),
+			ExpiresAt: entry.ExpiresAt,
+		})
+	}
+
+	bytes, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+func (c *TranslationCache) load() error {
+	if c.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	bytes, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return err
+	}
+
+	//entries are persisted from the least to the most recently used, so the recency order is preserved. Entries
+	//persisted without a style predate the styles and were translated to the default one
+	for _, entry := range file.Entries {
+		if !entry.ExpiresAt.IsZero() && !time.Now().Before(entry.ExpiresAt) {
+			continue
+		}
+		c.entries.SetWithExpiry(cacheKey(entry.Style, entry.Key), entry.Value, entry.ExpiresAt)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package translation_cache
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/translation/translation_domain"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type getTranslationProviderMock struct {
+	getShakespeareanTranslation func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error)
+}
+
+func (p *getTranslationProviderMock) Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+	return p.getShakespeareanTranslation(request)
+}
+
+//newTestCache creates a cache persisted in a temporary directory which has to be removed by the caller
+func newTestCache(t *testing.T) (*TranslationCache, string, string) {
+	dir, err := ioutil.TempDir("", "translation_cache")
+	assert.Nil(t, err)
+
+	path := filepath.Join(dir, "cache", "translations.json")
+	cache, err := New(Config{Path: path, TTL: time.Hour, MaxEntries: 10})
+	assert.Nil(t, err)
+	return cache, path, dir
+}
+
+func TestNormalizeKey(t *testing.T) {
+	assert.EqualValues(t, "Spits fire that is hot", NormalizeKey("  Spits fire\nthat\fis  hot "))
+}
+
+func TestCacheSurvivesRestart(t *testing.T) {
+	cache, path, dir := newTestCache(t)
+	defer os.RemoveAll(dir)
+
+	response := translation_domain.TranslationResponse{
+		Content: translation_domain.ContentFields{Translation: "Spits fire yond is hot enow"},
+	}
+	err := cache.Set("shakespeare", "Spits fire that\nis hot enough", response)
+	assert.Nil(t, err)
+
+	restored, err := New(Config{Path: path, TTL: time.Hour, MaxEntries: 10})
+	assert.Nil(t, err)
+
+	//the empty style stands for the default one
+	actualResponse, ok := restored.Get("", "Spits fire that is hot enough")
+	assert.True(t, ok)
+	assert.EqualValues(t, response, *actualResponse)
+	assert.EqualValues(t, 1, restored.Stats().Hits)
+}
+
+//Checks that concurrent writes do not drop an entry from disk, whatever the order their snapshots are written in
+func TestCacheConcurrentSets(t *testing.T) {
+	cache, path, dir := newTestCache(t)
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response := translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: strconv.Itoa(i)}}
+			assert.Nil(t, cache.Set("shakespeare", "text "+strconv.Itoa(i), response))
+		}(i)
+	}
+	wg.Wait()
+
+	restored, err := New(Config{Path: path, TTL: time.Hour, MaxEntries: 10})
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		_, ok := restored.Get("shakespeare", "text "+strconv.Itoa(i))
+		assert.True(t, ok, i)
+	}
+}
+
+func TestCacheKeyedByStyle(t *testing.T) {
+	cache, _, dir := newTestCache(t)
+	defer os.RemoveAll(dir)
+
+	shakespeare := translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: "Spits fire yond is hot"}}
+	yoda := translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: "Hot, the fire it spits is"}}
+	assert.Nil(t, cache.Set("shakespeare", "Spits fire that is hot", shakespeare))
+	assert.Nil(t, cache.Set("yoda", "Spits fire that is hot", yoda))
+
+	actualResponse, ok := cache.Get("yoda", "Spits fire that is hot")
+	assert.True(t, ok)
+	assert.EqualValues(t, yoda, *actualResponse)
+	actualResponse, ok = cache.Get("shakespeare", "Spits fire that is hot")
+	assert.True(t, ok)
+	assert.EqualValues(t, shakespeare, *actualResponse)
+	_, ok = cache.Get("pirate", "Spits fire that is hot")
+	assert.False(t, ok)
+}
+
+//Checks that the entries persisted before the styles existed are restored as Shakespearean translations
+func TestCacheRestoresEntriesWithoutStyle(t *testing.T) {
+	_, path, dir := newTestCache(t)
+	defer os.RemoveAll(dir)
+	err := ioutil.WriteFile(path, []byte(`{"entries": [{"key": "Spits fire", "value": {"contents": {"translated": "Spits fire"}}}]}`), 0644)
+	assert.Nil(t, err)
+
+	cache, err := New(Config{Path: path, TTL: time.Hour, MaxEntries: 10})
+	assert.Nil(t, err)
+	_, ok := cache.Get("shakespeare", "Spits fire")
+	assert.True(t, ok)
+}
+
+func TestCacheWithCorruptedFile(t *testing.T) {
+	_, path, dir := newTestCache(t)
+	defer os.RemoveAll(dir)
+	err := ioutil.WriteFile(path, []byte("{not json"), 0644)
+	assert.Nil(t, err)
+
+	cache, err := New(Config{Path: path})
+	assert.Nil(t, cache)
+	assert.NotNil(t, err)
+}
+
+func TestCachedProviderOnlyCallsProviderOnMiss(t *testing.T) {
+	cache, _, dir := newTestCache(t)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		calls++
+		return &translation_domain.TranslationResponse{
+			Content: translation_domain.ContentFields{Translation: "Lorem ipsum dolor sit amet"},
+		}, nil
+	}
+
+	provider := NewCachedProvider(&getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation}, cache)
+	for i := 0; i < 3; i++ {
+		response, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Lorem ipsum"})
+		assert.Nil(t, errorResponse)
+		assert.EqualValues(t, "Lorem ipsum dolor sit amet", response.Content.Translation)
+	}
+
+	assert.EqualValues(t, 1, calls)
+	assert.EqualValues(t, 2, cache.Stats().Hits)
+	assert.EqualValues(t, 1, cache.Stats().Misses)
+}
+
+func TestStaleProvider(t *testing.T) {
+	cache, err := New(Config{TTL: time.Millisecond, StaleTTL: time.Hour, MaxEntries: 10})
+	assert.Nil(t, err)
+	response := translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: "Lorem ipsum dolor sit amet"}}
+	assert.Nil(t, cache.Set("shakespeare", "Lorem ipsum", response))
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := cache.Get("shakespeare", "Lorem ipsum")
+	assert.False(t, ok)
+
+	provider := NewStaleProvider(cache)
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Lorem ipsum"})
+	assert.Nil(t, errorResponse)
+	assert.EqualValues(t, response, *actualResponse)
+
+	actualResponse, errorResponse = provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Dolor sit amet"})
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusNotFound, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeTranslationUnavailable, errorResponse.Code())
+}
+
+func TestCachedProviderDoesNotCacheErrors(t *testing.T) {
+	cache, _, dir := newTestCache(t)
+	defer os.RemoveAll(dir)
+
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		return nil, api_error.New(http.StatusTooManyRequests, api_error.CodeTranslationQuotaExceeded, "Too Many Requests")
+	}
+
+	provider := NewCachedProvider(&getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation}, cache)
+	response, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Lorem ipsum"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, http.StatusTooManyRequests, errorResponse.Status())
+	assert.EqualValues(t, 0, cache.Stats().Entries)
+}
@@ -1,26 +1,122 @@
 package restclient
 
-import "net/http"
+import (
+	"context"
+	"net"
+	"net/http"
+	"shakespearing-pokemon/api/logger"
+	"sync"
+	"time"
+)
 
-type clientStruct struct{}
+type clientStruct struct {
+	mu sync.Mutex
+	//clients holds one http client per connect timeout, since the connect timeout belongs to the transport
+	clients map[time.Duration]*http.Client
+	//jitter randomises the backoff of the retries
+	jitter func(d time.Duration) time.Duration
+}
 
 //ClientInterface used to mock calls in integration testing
 type ClientInterface interface {
-	Get(string) (*http.Response, error)
+	Get(ctx context.Context, url string, headers http.Header) (*http.Response, error)
 }
 
-var (
-	//ClientStruct used to mock calls in testing
-	ClientStruct ClientInterface = &clientStruct{}
-)
+//New creates a client, the connections it opens are reused by its later requests
+func New() ClientInterface {
+	return newClient(randomJitter)
+}
+
+func newClient(jitter func(d time.Duration) time.Duration) *clientStruct {
+	return &clientStruct{jitter: jitter}
+}
+
+type connectTimeoutKey struct{}
+
+//WithConnectTimeout returns a copy of ctx limiting the time spent establishing new connections, the time spent
+//waiting for the response is limited by the deadline of ctx
+func WithConnectTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, connectTimeoutKey{}, timeout)
+}
+
+func connectTimeout(ctx context.Context) time.Duration {
+	timeout, _ := ctx.Value(connectTimeoutKey{}).(time.Duration)
+	return timeout
+}
+
+//Get sends a GET request which is aborted as soon as ctx is done, transient failures are retried according to the
+//retry policy carried by ctx
+func (ci *clientStruct) Get(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+	client := ci.client(connectTimeout(ctx))
+	policy := retryPolicy(ctx)
 
-func (ci *clientStruct) Get(url string) (*http.Response, error) {
-	request, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
+	for attempt := 1; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range headers {
+			request.Header[key] = values
+		}
+
+		response, err := client.Do(request)
+		wait, retry := policy.backoff(ctx, attempt, response, err, ci.jitter)
+		if !retry {
+			return response, err
+		}
+
+		fields := logger.Fields{"url": url, "attempt": attempt, "backoff": wait.String()}
+		if err != nil {
+			fields["error"] = err
+		} else {
+			fields["status"] = response.StatusCode
+			response.Body.Close()
+		}
+		logger.FromContext(ctx).Warn("retrying call to external api", fields)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (ci *clientStruct) client(connectTimeout time.Duration) *http.Client {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+
+	if ci.clients == nil {
+		ci.clients = make(map[time.Duration]*http.Client)
+	}
+	if client, ok := ci.clients[connectTimeout]; ok {
+		return client
 	}
 
-	client := http.Client{}
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   connectTimeoutOr(connectTimeout, 10*time.Second),
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+	ci.clients[connectTimeout] = client
+	return client
+}
 
-	return client.Do(request)
+//connectTimeoutOr returns the connect timeout unless it is zero, i.e. unlimited
+func connectTimeoutOr(connectTimeout time.Duration, fallback time.Duration) time.Duration {
+	if connectTimeout > 0 {
+		return connectTimeout
+	}
+	return fallback
 }
@@ -0,0 +1,199 @@
+package restclient
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	headers := http.Header{}
+	headers.Set("X-Request-ID", "4f0c2a")
+	ctx := WithConnectTimeout(context.Background(), time.Second)
+	response, err := New().Get(ctx, server.URL, headers)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.EqualValues(t, http.StatusTeapot, response.StatusCode)
+	assert.EqualValues(t, "4f0c2a", response.Header.Get("X-Request-ID"))
+}
+
+func TestGetDeadlineExceeded(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	response, err := New().Get(ctx, server.URL, nil)
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClientPerConnectTimeout(t *testing.T) {
+	client := newClient(randomJitter)
+	assert.True(t, client.client(time.Second) == client.client(time.Second))
+	assert.False(t, client.client(time.Second) == client.client(2*time.Second))
+}
+
+//newFlakyServer answers with the given status codes in order and 200 afterwards
+func newFlakyServer(statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1))
+		if call <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return server, &calls
+}
+
+func retryContext() context.Context {
+	return WithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+}
+
+func TestGetRetriesTransientFailures(t *testing.T) {
+	server, calls := newFlakyServer(http.StatusServiceUnavailable, http.StatusBadGateway)
+	defer server.Close()
+
+	response, err := New().Get(retryContext(), server.URL, nil)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.EqualValues(t, http.StatusOK, response.StatusCode)
+	assert.EqualValues(t, 3, atomic.LoadInt32(calls))
+}
+
+func TestGetGivesUpAfterMaxAttempts(t *testing.T) {
+	server, calls := newFlakyServer(http.StatusGatewayTimeout, http.StatusGatewayTimeout, http.StatusGatewayTimeout)
+	defer server.Close()
+
+	response, err := New().Get(retryContext(), server.URL, nil)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.EqualValues(t, http.StatusGatewayTimeout, response.StatusCode)
+	assert.EqualValues(t, 3, atomic.LoadInt32(calls))
+}
+
+func TestGetDoesNotRetry(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError} {
+		server, calls := newFlakyServer(status)
+
+		response, err := New().Get(retryContext(), server.URL, nil)
+		assert.Nil(t, err)
+		response.Body.Close()
+		assert.EqualValues(t, status, response.StatusCode)
+		assert.EqualValues(t, 1, atomic.LoadInt32(calls))
+		server.Close()
+	}
+}
+
+func TestGetWithoutRetryPolicy(t *testing.T) {
+	server, calls := newFlakyServer(http.StatusServiceUnavailable)
+	defer server.Close()
+
+	response, err := New().Get(context.Background(), server.URL, nil)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.EqualValues(t, 1, atomic.LoadInt32(calls))
+}
+
+func TestGetRetriesNetworkErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			//resets the connection without answering
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.Nil(t, err)
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	response, err := New().Get(retryContext(), server.URL, nil)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.EqualValues(t, http.StatusOK, response.StatusCode)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestBackoff(t *testing.T) {
+	jitter := func(d time.Duration) time.Duration { return d - 1 }
+
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	unavailable := func(retryAfter string) *http.Response {
+		response := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+		if retryAfter != "" {
+			response.Header.Set("Retry-After", retryAfter)
+		}
+		return response
+	}
+
+	testCases := []struct {
+		name     string
+		attempt  int
+		response *http.Response
+		err      error
+		wait     time.Duration
+		retry    bool
+	}{
+		{name: "first retry", attempt: 1, response: unavailable(""), wait: 100*time.Millisecond - 1, retry: true},
+		{name: "doubles", attempt: 3, response: unavailable(""), wait: 400*time.Millisecond - 1, retry: true},
+		{name: "capped", attempt: 8, response: unavailable(""), wait: time.Second - 1, retry: true},
+		{name: "network error", attempt: 1, err: errors.New("connection reset by peer"), wait: 100*time.Millisecond - 1, retry: true},
+		{name: "retry after", attempt: 1, response: unavailable("1"), wait: time.Second, retry: true},
+		{name: "retry after too long", attempt: 1, response: unavailable("120"), retry: false},
+		{name: "max attempts", attempt: 10, response: unavailable(""), retry: false},
+		{name: "not found", attempt: 1, response: &http.Response{StatusCode: http.StatusNotFound}, retry: false},
+	}
+
+	for _, testCase := range testCases {
+		wait, retry := policy.backoff(context.Background(), testCase.attempt, testCase.response, testCase.err, jitter)
+		assert.EqualValues(t, testCase.retry, retry, testCase.name)
+		assert.EqualValues(t, testCase.wait, wait, testCase.name)
+	}
+}
+
+func TestBackoffBeyondDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Second}
+	_, retry := policy.backoff(ctx, 1, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, randomJitter)
+	assert.False(t, retry)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, time.October, 10, 12, 0, 0, 0, time.UTC)
+
+	wait, ok := parseRetryAfter("30", now)
+	assert.True(t, ok)
+	assert.EqualValues(t, 30*time.Second, wait)
+
+	wait, ok = parseRetryAfter("Sat, 10 Oct 2020 12:01:00 GMT", now)
+	assert.True(t, ok)
+	assert.EqualValues(t, time.Minute, wait)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,108 @@
+package restclient
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//RetryPolicy describes how the GET requests failing with a network error or a 502, 503 or 504 are retried, any other
+//response, e.g. a 404 or a 429 signalling that the quota is spent, is returned as is.
+//The backoff doubles after every attempt up to MaxBackoff and is randomised to spread the retries of concurrent calls,
+//a Retry-After header replaces the backoff unless it asks to wait longer than MaxBackoff, in which case the response
+//is returned
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+type retryPolicyKey struct{}
+
+//WithRetryPolicy returns a copy of ctx retrying the requests according to policy, requests are not retried otherwise
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+func retryPolicy(ctx context.Context) RetryPolicy {
+	policy, _ := ctx.Value(retryPolicyKey{}).(RetryPolicy)
+	return policy
+}
+
+//randomJitter returns a random duration in [0, d)
+func randomJitter(d time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+//backoff reports whether the attempt which returned response and err is retried and how long to wait before doing so,
+//randomised by jitter. A retry which would not start before the deadline of ctx is not attempted
+func (p RetryPolicy) backoff(ctx context.Context, attempt int, response *http.Response, err error, jitter func(d time.Duration) time.Duration) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+	if err == nil && !retryableStatus(response.StatusCode) {
+		return 0, false
+	}
+
+	wait := p.exponentialBackoff(attempt, jitter)
+	if err == nil {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+			if retryAfter > p.MaxBackoff {
+				return 0, false
+			}
+			wait = retryAfter
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+	return wait, true
+}
+
+//exponentialBackoff returns a random duration between half and the whole of the capped exponential backoff
+func (p RetryPolicy) exponentialBackoff(attempt int, jitter func(d time.Duration) time.Duration) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 1 {
+		return backoff
+	}
+	return backoff/2 + jitter(backoff/2)
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+//RetryAfter reads the Retry-After header of the response, given either in seconds or as an http date
+func RetryAfter(response *http.Response) (time.Duration, bool) {
+	return parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+}
+
+//parseRetryAfter reads a Retry-After header given either in seconds or as an http date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
@@ -0,0 +1,155 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/logger"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"strings"
+	"time"
+)
+
+//Names identify an external api: Provider labels the logs and metrics of the provider calling it, Upstream tells the
+//clients which external api failed their request, Title names the api and Results what it returns in the error messages
+type Names struct {
+	Provider string
+	Upstream string
+	Title    string
+	Results  string
+}
+
+//Upstream calls an external api through the rest client, every upstream has its own circuit breaker so that
+//providers configured differently do not affect each other
+type Upstream struct {
+	client  restclient.ClientInterface
+	names   Names
+	config  config.UpstreamConfig
+	breaker *circuitbreaker.Breaker
+	metrics *metrics.Registry
+}
+
+//New creates an upstream calling the deployment of the config through the client, its calls are recorded to the
+//registry
+func New(client restclient.ClientInterface, names Names, cfg config.UpstreamConfig, registry *metrics.Registry) *Upstream {
+	cfg.BaseUrl = strings.TrimSuffix(cfg.BaseUrl, "/")
+	return &Upstream{
+		client:  client,
+		names:   names,
+		config:  cfg,
+		breaker: circuitbreaker.New(cfg.CircuitBreaker.BreakerConfig()),
+		metrics: registry,
+	}
+}
+
+//BaseUrl returns the url of the deployment the upstream calls
+func (u *Upstream) BaseUrl() string {
+	return u.config.BaseUrl
+}
+
+//CircuitBreakerState returns the state of the circuit breaker guarding the external api
+func (u *Upstream) CircuitBreakerState() circuitbreaker.State {
+	return u.breaker.State()
+}
+
+//Get calls the url within the timeouts and retry policy of the config and reads the response, whatever its status.
+//Once the circuit breaker lets the call through, before is called if not nil and the call is not made if it fails.
+//The response timeout starts after before returned, so that waiting in before does not shorten the call
+func (u *Upstream) Get(ctx context.Context, url string, before func(ctx context.Context) *api_error.Error) (*http.Response, []byte, *api_error.Error) {
+	wait, ok := u.breaker.Allow()
+	if !ok {
+		return nil, nil, api_error.New(http.StatusServiceUnavailable, api_error.CodeUpstreamUnavailable,
+			fmt.Sprintf("the %s is unavailable, circuit breaker is open", u.names.Title)).
+			WithRetryAfter(ratelimit.RetryAfterSeconds(wait)).WithUpstream(u.names.Upstream)
+	}
+
+	if before != nil {
+		if errorResponse := before(ctx); errorResponse != nil {
+			//the call is not made, hence it says nothing about the health of the external api
+			u.breaker.Release()
+			return nil, nil, errorResponse
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.config.ResponseTimeout.Duration)
+	defer cancel()
+	ctx = restclient.WithConnectTimeout(ctx, u.config.ConnectTimeout.Duration)
+	ctx = restclient.WithRetryPolicy(ctx, u.config.Retry.RetryPolicy())
+
+	headers := http.Header{}
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		headers.Set(logger.RequestIDHeader, requestID)
+	}
+
+	start := time.Now()
+	response, err := u.client.Get(ctx, url, headers)
+	u.metrics.ObserveUpstreamRequest(u.names.Provider, response, time.Since(start))
+	u.recordOutcome(ctx, response, err)
+	if errorResponse := u.Error(ctx, err, "error when trying to get "+u.names.Results+" results"); errorResponse != nil {
+		return nil, nil, errorResponse
+	}
+
+	bytes, err := ioutil.ReadAll(response.Body)
+	defer response.Body.Close()
+	if errorResponse := u.Error(ctx, err, "error when parsing the "+u.names.Results+" response body"); errorResponse != nil {
+		return nil, nil, errorResponse
+	}
+
+	if response.StatusCode > 299 {
+		logger.FromContext(ctx).Warn("unexpected status code from external api", logger.Fields{
+			"provider": u.names.Provider,
+			"status":   response.StatusCode,
+		})
+	}
+	return response, bytes, nil
+}
+
+//Decode unmarshals the body of a successful response into result, what names the response in the error message
+func (u *Upstream) Decode(ctx context.Context, body []byte, result interface{}, what string) *api_error.Error {
+	err := json.Unmarshal(body, result)
+	if err != nil {
+		logger.FromContext(ctx).Error("error when trying to unmarshal "+what+" from API", logger.Fields{"provider": u.names.Provider, "error": err})
+		return api_error.Wrap(err, http.StatusBadGateway, api_error.CodeUpstreamBadResponse,
+			"error when trying to unmarshal "+what+" from API: "+err.Error()).WithUpstream(u.names.Upstream)
+	}
+	return nil
+}
+
+//Error logs and reports the failure of a call to the external api, nil if err is nil
+func (u *Upstream) Error(ctx context.Context, err error, errorMsg string) *api_error.Error {
+	if err != nil {
+		logger.FromContext(ctx).Error(errorMsg, logger.Fields{"provider": u.names.Provider, "error": err})
+		return api_error.NewUpstreamError(u.names.Upstream, err, fmt.Sprintf(errorMsg+": %s", err.Error()))
+	}
+	return nil
+}
+
+//StatusError reports the unexpected status the external api responded with, its Retry-After header is passed through
+func (u *Upstream) StatusError(response *http.Response, message string) *api_error.Error {
+	retryAfter := 0
+	if wait, ok := restclient.RetryAfter(response); ok {
+		retryAfter = ratelimit.RetryAfterSeconds(wait)
+	}
+	return api_error.NewUpstreamStatusError(u.names.Upstream, response.StatusCode, retryAfter, message)
+}
+
+//recordOutcome feeds the circuit breaker, network errors, server errors and 429s count as failures while a call
+//abandoned by every caller says nothing about the health of the external api
+func (u *Upstream) recordOutcome(ctx context.Context, response *http.Response, err error) {
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		u.breaker.Release()
+	case err != nil || response.StatusCode >= http.StatusInternalServerError || response.StatusCode == http.StatusTooManyRequests:
+		u.breaker.Failure()
+	default:
+		u.breaker.Success()
+	}
+}
@@ -0,0 +1,102 @@
+package upstream
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testNames = Names{Provider: "test_provider", Upstream: "test", Title: "test API", Results: "test"}
+
+//newTestUpstream calls the server without retries, its circuit breaker opens after two failures
+func newTestUpstream(server *httptest.Server) *Upstream {
+	cfg := config.Default().PokeAPI
+	cfg.BaseUrl = server.URL + "/"
+	cfg.Retry = config.RetryConfig{}
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{FailureThreshold: 2, CoolDown: config.Duration{Duration: time.Minute}}
+	return New(restclient.New(), testNames, cfg, metrics.NewRegistry())
+}
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"name":"charizard"}`))
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstream(server)
+	assert.EqualValues(t, server.URL, upstream.BaseUrl())
+	response, body, errorResponse := upstream.Get(context.Background(), server.URL, nil)
+	assert.Nil(t, errorResponse)
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.EqualValues(t, `{"name":"charizard"}`, string(body))
+
+	statusError := upstream.StatusError(response, "error from external api")
+	assert.EqualValues(t, api_error.CodeUpstreamUnavailable, statusError.Code())
+	assert.EqualValues(t, 7, statusError.RetryAfter())
+	assert.EqualValues(t, "test", statusError.Upstream())
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	assert.Nil(t, upstream.Decode(context.Background(), body, &result, "test response"))
+	assert.EqualValues(t, "charizard", result.Name)
+
+	decodeError := upstream.Decode(context.Background(), []byte("]"), &result, "test response")
+	assert.NotNil(t, decodeError)
+	assert.EqualValues(t, api_error.CodeUpstreamBadResponse, decodeError.Code())
+	assert.EqualValues(t, "error when trying to unmarshal test response from API: invalid character ']' looking for beginning of value", decodeError.Message())
+}
+
+func TestGetOpensCircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstream(server)
+	for i := 0; i < 2; i++ {
+		_, _, errorResponse := upstream.Get(context.Background(), server.URL, nil)
+		assert.Nil(t, errorResponse)
+	}
+	assert.EqualValues(t, circuitbreaker.Open, upstream.CircuitBreakerState())
+
+	_, _, errorResponse := upstream.Get(context.Background(), server.URL, nil)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, api_error.CodeUpstreamUnavailable, errorResponse.Code())
+	assert.EqualValues(t, "the test API is unavailable, circuit breaker is open", errorResponse.Message())
+	assert.EqualValues(t, 60, errorResponse.RetryAfter())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestGetRejectedBeforeTheCall(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	upstream := newTestUpstream(server)
+	rejected := api_error.New(http.StatusTooManyRequests, api_error.CodeTranslationQuotaExceeded, "quota spent")
+	for i := 0; i < 3; i++ {
+		_, _, errorResponse := upstream.Get(context.Background(), server.URL, func(ctx context.Context) *api_error.Error {
+			return rejected
+		})
+		assert.True(t, errorResponse == rejected)
+	}
+	//the calls which were not made do not count as failures
+	assert.EqualValues(t, circuitbreaker.Closed, upstream.CircuitBreakerState())
+	assert.EqualValues(t, 0, atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,454 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
+	"shakespearing-pokemon/api/logger"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"strings"
+	"time"
+)
+
+const (
+	//EnvPrefix is prepended to the name of every environment variable overriding the configuration
+	EnvPrefix = "SHAKESPEARE_POKEMON_"
+)
+
+//Config holds every setting of the application, it is read from a json file in the form of:
+//	{
+//		"server": {
+//			"address": ":8080",
+//			"mode": "release",
+//			"shutdown_timeout": "30s"
+//		},
+//		"pokeapi": {
+//			"base_url": "https://pokeapi.co/api/v2",
+//			"connect_timeout": "2s",
+//			"response_timeout": "10s",
+//			"retry": {
+//				"max_attempts": 3,
+//				"initial_backoff": "100ms",
+//				"max_backoff": "2s"
+//			},
+//			"circuit_breaker": {
+//				"failure_threshold": 5,
+//				"cool_down": "30s"
+//			}
+//		},
+//		"translation": {
+//			"base_url": "https://api.funtranslations.com/translate",
+//			"connect_timeout": "2s",
+//			"response_timeout": "10s",
+//			"retry": {
+//				"max_attempts": 1,
+//				"initial_backoff": "200ms",
+//				"max_backoff": "2s"
+//			},
+//			"circuit_breaker": {
+//				"failure_threshold": 5,
+//				"cool_down": "30s"
+//			},
+//			"quota": {
+//				"limit": 5,
+//				"window": "1h",
+//				"max_wait": "0s"
+//			},
+//			"fallback": ["funtranslations", "cache", "local", "original"]
+//		},
+//		"rate_limit": {
+//			"limit": 60,
+//			"window": "1m",
+//			"max_clients": 10000,
+//			"api_keys": [],
+//			"trusted_proxies": ["10.0.0.0/8"]
+//		},
+//		"batch": {
+//			"max_items": 50,
+//			"workers": 8
+//		},
+//		"log": {
+//			"level": "info",
+//			"path": ""
+//		},
+//		"cache": {
+//			"translation": {
+//				"path": "data/translation_cache.json",
+//				"ttl": "720h",
+//				"stale_ttl": "168h",
+//				"max_entries": 10000
+//			},
+//			"pokemon": {
+//				"ttl": "24h",
+//				"negative_ttl": "5m",
+//				"max_entries": 1000
+//			}
+//		}
+//	}
+//every field is optional and can be overridden by environment variables and command line flags
+type Config struct {
+	Server      ServerConfig      `json:"server"`
+	PokeAPI     UpstreamConfig    `json:"pokeapi"`
+	Translation TranslationConfig `json:"translation"`
+	RateLimit   RateLimitConfig   `json:"rate_limit"`
+	Batch       BatchConfig       `json:"batch"`
+	Log         LogConfig         `json:"log"`
+	Cache       CacheConfig       `json:"cache"`
+}
+
+//ServerConfig tells where the server listens, on shutdown the in-flight requests are given up to ShutdownTimeout to
+//complete
+type ServerConfig struct {
+	Address         string   `json:"address"`
+	Mode            string   `json:"mode"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+}
+
+//UpstreamConfig describes how to reach an external api, the connect timeout limits the time spent establishing a
+//connection while the response timeout limits the whole call, including its retries
+type UpstreamConfig struct {
+	BaseUrl         string               `json:"base_url"`
+	ConnectTimeout  Duration             `json:"connect_timeout"`
+	ResponseTimeout Duration             `json:"response_timeout"`
+	Retry           RetryConfig          `json:"retry"`
+	CircuitBreaker  CircuitBreakerConfig `json:"circuit_breaker"`
+}
+
+//TranslationConfig describes how to reach the FunTranslations API and how to spend its quota, Fallback lists the
+//translation engines tried in order until one of them translates a description. A call takes a single token of the
+//quota whatever its attempts, hence the calls are not retried by default: every retry would spend the quota of the
+//api without the quota accounting for it
+type TranslationConfig struct {
+	UpstreamConfig
+	Quota    QuotaConfig `json:"quota"`
+	Fallback []string    `json:"fallback"`
+}
+
+//QuotaConfig allows Limit calls per Window, a call waits up to MaxWait for the quota before being rejected, the wait
+//does not count against the response timeout of the call
+type QuotaConfig struct {
+	Limit   int      `json:"limit"`
+	Window  Duration `json:"window"`
+	MaxWait Duration `json:"max_wait"`
+}
+
+//RetryConfig describes how transient failures of an external api are retried, a single attempt disables the retries
+type RetryConfig struct {
+	MaxAttempts    int      `json:"max_attempts"`
+	InitialBackoff Duration `json:"initial_backoff"`
+	MaxBackoff     Duration `json:"max_backoff"`
+}
+
+//CircuitBreakerConfig defines how many consecutive failures stop the calls to an external api and for how long
+type CircuitBreakerConfig struct {
+	FailureThreshold int      `json:"failure_threshold"`
+	CoolDown         Duration `json:"cool_down"`
+}
+
+//RetryPolicy returns the policy the rest client retries the calls with
+func (r RetryConfig) RetryPolicy() restclient.RetryPolicy {
+	return restclient.RetryPolicy{
+		MaxAttempts:    r.MaxAttempts,
+		InitialBackoff: r.InitialBackoff.Duration,
+		MaxBackoff:     r.MaxBackoff.Duration,
+	}
+}
+
+//BreakerConfig returns the configuration of the circuit breaker guarding the external api
+func (c CircuitBreakerConfig) BreakerConfig() circuitbreaker.Config {
+	return circuitbreaker.Config{
+		FailureThreshold: c.FailureThreshold,
+		CoolDown:         c.CoolDown.Duration,
+	}
+}
+
+//LimiterConfig returns the configuration of the rate limiter spending the quota
+func (q QuotaConfig) LimiterConfig() ratelimit.Config {
+	return ratelimit.Config{
+		Limit:  q.Limit,
+		Window: q.Window.Duration,
+	}
+}
+
+//RateLimitConfig allows every client Limit requests per Window, the limits of up to MaxClients clients are tracked.
+//A client sending one of the APIKeys has its own limit, any other client is identified by its ip, read from the
+//X-Forwarded-For header only when the request comes from one of the TrustedProxies, given as ips or CIDR networks
+type RateLimitConfig struct {
+	Limit          int      `json:"limit"`
+	Window         Duration `json:"window"`
+	MaxClients     int      `json:"max_clients"`
+	APIKeys        []string `json:"api_keys"`
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+//BatchConfig limits a batch request to MaxItems pokemon, each of them counting as a request against the rate limit, and
+//the pokemon translated concurrently by all the batches to Workers
+type BatchConfig struct {
+	MaxItems int `json:"max_items"`
+	Workers  int `json:"workers"`
+}
+
+//LogConfig sets the minimum level of the logs and the file they are appended to, the standard output when Path is empty
+type LogConfig struct {
+	Level string `json:"level"`
+	Path  string `json:"path"`
+}
+
+type CacheConfig struct {
+	Translation TranslationCacheConfig `json:"translation"`
+	Pokemon     PokemonCacheConfig     `json:"pokemon"`
+}
+
+type TranslationCacheConfig struct {
+	Path       string   `json:"path"`
+	TTL        Duration `json:"ttl"`
+	StaleTTL   Duration `json:"stale_ttl"`
+	MaxEntries int      `json:"max_entries"`
+}
+
+type PokemonCacheConfig struct {
+	TTL         Duration `json:"ttl"`
+	NegativeTTL Duration `json:"negative_ttl"`
+	MaxEntries  int      `json:"max_entries"`
+}
+
+//Default returns the configuration used when no file, environment variable or flag overrides it
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			Mode:            gin.DebugMode,
+			ShutdownTimeout: Duration{30 * time.Second},
+		},
+		PokeAPI: UpstreamConfig{
+			BaseUrl:         "https://pokeapi.co/api/v2",
+			ConnectTimeout:  Duration{2 * time.Second},
+			ResponseTimeout: Duration{10 * time.Second},
+			Retry: RetryConfig{
+				MaxAttempts:    3,
+				InitialBackoff: Duration{100 * time.Millisecond},
+				MaxBackoff:     Duration{2 * time.Second},
+			},
+			CircuitBreaker: CircuitBreakerConfig{
+				FailureThreshold: 5,
+				CoolDown:         Duration{30 * time.Second},
+			},
+		},
+		Translation: TranslationConfig{
+			UpstreamConfig: UpstreamConfig{
+				BaseUrl:         "https://api.funtranslations.com/translate",
+				ConnectTimeout:  Duration{2 * time.Second},
+				ResponseTimeout: Duration{10 * time.Second},
+				Retry: RetryConfig{
+					MaxAttempts:    1,
+					InitialBackoff: Duration{200 * time.Millisecond},
+					MaxBackoff:     Duration{2 * time.Second},
+				},
+				CircuitBreaker: CircuitBreakerConfig{
+					FailureThreshold: 5,
+					CoolDown:         Duration{30 * time.Second},
+				},
+			},
+			Quota: QuotaConfig{
+				Limit:  5,
+				Window: Duration{time.Hour},
+			},
+			Fallback: []string{
+				shksprean_pokemon_domain.EngineFunTranslations,
+				shksprean_pokemon_domain.EngineCache,
+				shksprean_pokemon_domain.EngineLocal,
+				shksprean_pokemon_domain.EngineOriginal,
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Limit:      60,
+			Window:     Duration{time.Minute},
+			MaxClients: 10000,
+		},
+		Batch: BatchConfig{
+			MaxItems: 50,
+			Workers:  8,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Cache: CacheConfig{
+			Translation: TranslationCacheConfig{
+				Path:       "data/translation_cache.json",
+				TTL:        Duration{30 * 24 * time.Hour},
+				StaleTTL:   Duration{7 * 24 * time.Hour},
+				MaxEntries: 10000,
+			},
+			Pokemon: PokemonCacheConfig{
+				TTL:         Duration{24 * time.Hour},
+				NegativeTTL: Duration{5 * time.Minute},
+				MaxEntries:  1000,
+			},
+		},
+	}
+}
+
+//Load builds the configuration from the defaults, the mode being taken from GIN_MODE when set, then the json file given by the -config flag or the
+//SHAKESPEARE_POKEMON_CONFIG environment variable, then the environment variables and finally the command line flags.
+//The resulting configuration is validated
+func Load(args []string) (*Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	defaultConfigPath, _ := lookupEnv(EnvPrefix + "CONFIG")
+
+	flags := flag.NewFlagSet("shakespearing-pokemon", flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "path of the json configuration file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.name] = flags.String(s.name, "", s.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	//GIN_MODE was the only way to set the mode before it was configurable, it keeps overriding the default
+	if mode, ok := lookupEnv(gin.EnvGinMode); ok && mode != "" {
+		config.Server.Mode = mode
+	}
+	if *configPath != "" {
+		if err := config.readFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		env := s.env()
+		if value, ok := lookupEnv(env); ok {
+			if err := s.apply(config, value); err != nil {
+				return nil, fmt.Errorf("invalid value for environment variable %s: %s", env, err.Error())
+			}
+		}
+	}
+
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		s, ok := settingsByName[f.Name]
+		if !ok || err != nil {
+			return
+		}
+		if applyErr := s.apply(config, *flagValues[f.Name]); applyErr != nil {
+			err = fmt.Errorf("invalid value for flag -%s: %s", f.Name, applyErr.Error())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) readFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error when reading the configuration file: %s", err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("error when parsing the configuration file %s: %s", path, err.Error())
+	}
+	return nil
+}
+
+//Validate checks every setting and reports all the invalid ones at once
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(valid bool, problem string) {
+		if !valid {
+			problems = append(problems, problem)
+		}
+	}
+
+	check(c.Server.Address != "", "server.address cannot be empty")
+	check(c.Server.Mode == gin.DebugMode || c.Server.Mode == gin.ReleaseMode || c.Server.Mode == gin.TestMode,
+		fmt.Sprintf("server.mode must be one of %s, %s or %s", gin.DebugMode, gin.ReleaseMode, gin.TestMode))
+	check(c.Server.ShutdownTimeout.Duration > 0, "server.shutdown_timeout must be positive")
+	c.PokeAPI.validate("pokeapi", check)
+	c.Translation.validate("translation", check)
+	check(c.Translation.Quota.Limit > 0, "translation.quota.limit must be positive")
+	check(c.Translation.Quota.Window.Duration > 0, "translation.quota.window must be positive")
+	check(c.Translation.Quota.MaxWait.Duration >= 0, "translation.quota.max_wait cannot be negative")
+	c.validateFallback(check)
+	check(c.RateLimit.Limit > 0, "rate_limit.limit must be positive")
+	check(c.RateLimit.Window.Duration > 0, "rate_limit.window must be positive")
+	check(c.RateLimit.MaxClients > 0, "rate_limit.max_clients must be positive")
+	for _, apiKey := range c.RateLimit.APIKeys {
+		check(apiKey != "", "rate_limit.api_keys cannot contain an empty key")
+	}
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, fmt.Sprintf("rate_limit.trusted_proxies entry %q must be an ip or a CIDR network", proxy))
+	}
+	check(c.Batch.MaxItems > 0, "batch.max_items must be positive")
+	check(c.Batch.Workers > 0, "batch.workers must be positive")
+	check(c.Batch.MaxItems <= c.RateLimit.Limit, "batch.max_items cannot exceed rate_limit.limit, a full batch would never be allowed")
+	_, err := logger.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level must be one of debug, info, warn or error")
+	check(c.Cache.Translation.TTL.Duration > 0, "cache.translation.ttl must be positive")
+	check(c.Cache.Translation.StaleTTL.Duration >= 0, "cache.translation.stale_ttl cannot be negative")
+	check(c.Cache.Translation.MaxEntries > 0, "cache.translation.max_entries must be positive")
+	check(c.Cache.Pokemon.TTL.Duration > 0, "cache.pokemon.ttl must be positive")
+	check(c.Cache.Pokemon.NegativeTTL.Duration >= 0, "cache.pokemon.negative_ttl cannot be negative")
+	check(c.Cache.Pokemon.MaxEntries > 0, "cache.pokemon.max_entries must be positive")
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c *Config) validateFallback(check func(valid bool, problem string)) {
+	check(len(c.Translation.Fallback) > 0, "translation.fallback cannot be empty")
+	seen := make(map[string]bool, len(c.Translation.Fallback))
+	for _, engine := range c.Translation.Fallback {
+		known := false
+		for _, e := range shksprean_pokemon_domain.Engines {
+			known = known || e == engine
+		}
+		check(known, fmt.Sprintf("translation.fallback engine %q must be one of %s", engine, strings.Join(shksprean_pokemon_domain.Engines, ", ")))
+		check(!seen[engine], fmt.Sprintf("translation.fallback engine %q is listed more than once", engine))
+		seen[engine] = true
+	}
+}
+
+func (u UpstreamConfig) validate(name string, check func(valid bool, problem string)) {
+	check(validUrl(u.BaseUrl), name+".base_url must be an absolute http or https url")
+	check(u.ConnectTimeout.Duration > 0, name+".connect_timeout must be positive")
+	check(u.ResponseTimeout.Duration > 0, name+".response_timeout must be positive")
+	check(u.ConnectTimeout.Duration <= u.ResponseTimeout.Duration, name+".connect_timeout cannot exceed "+name+".response_timeout")
+	check(u.Retry.MaxAttempts > 0, name+".retry.max_attempts must be positive")
+	check(u.Retry.InitialBackoff.Duration > 0, name+".retry.initial_backoff must be positive")
+	check(u.Retry.MaxBackoff.Duration >= u.Retry.InitialBackoff.Duration, name+".retry.max_backoff cannot be lower than "+name+".retry.initial_backoff")
+	check(u.CircuitBreaker.FailureThreshold > 0, name+".circuit_breaker.failure_threshold must be positive")
+	check(u.CircuitBreaker.CoolDown.Duration > 0, name+".circuit_breaker.cool_down must be positive")
+}
+
+func validUrl(rawUrl string) bool {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
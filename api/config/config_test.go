@@ -0,0 +1,221 @@
+package config
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func lookupEnvMock(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "config*.json")
+	assert.Nil(t, err)
+	_, err = file.WriteString(content)
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+	return file.Name()
+}
+
+func TestLoadDefaults(t *testing.T) {
+	config, err := load([]string{}, lookupEnvMock(nil))
+	assert.Nil(t, err)
+	assert.EqualValues(t, Default(), config)
+}
+
+func TestLoadGinMode(t *testing.T) {
+	config, err := load([]string{}, lookupEnvMock(map[string]string{"GIN_MODE": "release"}))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "release", config.Server.Mode)
+
+	//the configured mode wins over GIN_MODE
+	config, err = load([]string{"-mode", "test"}, lookupEnvMock(map[string]string{"GIN_MODE": "release"}))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "test", config.Server.Mode)
+}
+
+func TestLoadOverridesInOrder(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"server": {"address": ":9090", "mode": "release"},
+		"pokeapi": {"base_url": "http://localhost:8081"},
+		"cache": {"pokemon": {"ttl": "1h"}}
+	}`)
+	defer os.Remove(path)
+
+	env := map[string]string{
+		EnvPrefix + "CONFIG":            path,
+		EnvPrefix + "ADDRESS":           ":7070",
+		EnvPrefix + "POKEMON_CACHE_TTL": "2h",
+	}
+
+	config, err := load([]string{"-address", ":6060"}, lookupEnvMock(env))
+	assert.Nil(t, err)
+	//flag overrides environment variable which overrides file
+	assert.EqualValues(t, ":6060", config.Server.Address)
+	//environment variable overrides file
+	assert.EqualValues(t, 2*time.Hour, config.Cache.Pokemon.TTL.Duration)
+	//file overrides defaults
+	assert.EqualValues(t, "release", config.Server.Mode)
+	assert.EqualValues(t, "http://localhost:8081", config.PokeAPI.BaseUrl)
+	//defaults are kept for everything else
+	assert.EqualValues(t, Default().Translation, config.Translation)
+	assert.EqualValues(t, Default().Cache.Translation, config.Cache.Translation)
+}
+
+func TestLoadTranslationFile(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"translation": {"base_url": "http://localhost:8082", "quota": {"limit": 100, "max_wait": "2s"}}
+	}`)
+	defer os.Remove(path)
+
+	config, err := load([]string{"-config", path, "-translation-quota-window", "24h"}, lookupEnvMock(nil))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "http://localhost:8082", config.Translation.BaseUrl)
+	assert.EqualValues(t, Default().Translation.Retry, config.Translation.Retry)
+	assert.EqualValues(t, QuotaConfig{Limit: 100, Window: Duration{24 * time.Hour}, MaxWait: Duration{2 * time.Second}}, config.Translation.Quota)
+}
+
+func TestLoadRateLimit(t *testing.T) {
+	env := map[string]string{EnvPrefix + "RATE_LIMIT_WINDOW": "1h"}
+	config, err := load([]string{"-rate-limit", "100"}, lookupEnvMock(env))
+	assert.Nil(t, err)
+	assert.EqualValues(t, RateLimitConfig{Limit: 100, Window: Duration{time.Hour}, MaxClients: 10000}, config.RateLimit)
+
+	env = map[string]string{EnvPrefix + "RATE_LIMIT_API_KEYS": "4f0c2a, 9b7e1d"}
+	config, err = load([]string{"-rate-limit-trusted-proxies", "10.0.0.0/8,192.0.2.1"}, lookupEnvMock(env))
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"4f0c2a", "9b7e1d"}, config.RateLimit.APIKeys)
+	assert.EqualValues(t, []string{"10.0.0.0/8", "192.0.2.1"}, config.RateLimit.TrustedProxies)
+
+	_, err = load([]string{"-rate-limit-trusted-proxies", "gateway", "-rate-limit-api-keys", "4f0c2a,"}, lookupEnvMock(nil))
+	assert.EqualValues(t, "invalid configuration: rate_limit.api_keys cannot contain an empty key; "+
+		`rate_limit.trusted_proxies entry "gateway" must be an ip or a CIDR network`, err.Error())
+}
+
+func TestLoadShutdown(t *testing.T) {
+	env := map[string]string{EnvPrefix + "LOG_PATH": "/var/log/shakespearing-pokemon.log"}
+	config, err := load([]string{"-shutdown-timeout", "1m"}, lookupEnvMock(env))
+	assert.Nil(t, err)
+	assert.EqualValues(t, time.Minute, config.Server.ShutdownTimeout.Duration)
+	assert.EqualValues(t, "/var/log/shakespearing-pokemon.log", config.Log.Path)
+
+	config, err = load([]string{"-shutdown-timeout", "0s"}, lookupEnvMock(nil))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, "invalid configuration: server.shutdown_timeout must be positive", err.Error())
+}
+
+func TestLoadBatch(t *testing.T) {
+	env := map[string]string{EnvPrefix + "BATCH_WORKERS": "4"}
+	config, err := load([]string{"-batch-max-items", "20"}, lookupEnvMock(env))
+	assert.Nil(t, err)
+	assert.EqualValues(t, BatchConfig{MaxItems: 20, Workers: 4}, config.Batch)
+
+	config, err = load([]string{"-batch-workers", "0"}, lookupEnvMock(nil))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, "invalid configuration: batch.workers must be positive", err.Error())
+}
+
+func TestLoadTranslationFallback(t *testing.T) {
+	config, err := load([]string{"-translation-fallback", "local, original"}, lookupEnvMock(nil))
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"local", "original"}, config.Translation.Fallback)
+
+	config, err = load([]string{"-translation-fallback", "funtranslations,oracle,funtranslations"}, lookupEnvMock(nil))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, `invalid configuration: translation.fallback engine "oracle" must be one of funtranslations, cache, local, original; `+
+		`translation.fallback engine "funtranslations" is listed more than once`, err.Error())
+}
+
+func TestLoadEmptyList(t *testing.T) {
+	env := map[string]string{EnvPrefix + "RATE_LIMIT_API_KEYS": ""}
+	config, err := load([]string{"-rate-limit-trusted-proxies= "}, lookupEnvMock(env))
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{}, config.RateLimit.APIKeys)
+	assert.EqualValues(t, []string{}, config.RateLimit.TrustedProxies)
+
+	config, err = load([]string{"-translation-fallback="}, lookupEnvMock(nil))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, "invalid configuration: translation.fallback cannot be empty", err.Error())
+}
+
+func TestLoadEmptyTranslationCachePath(t *testing.T) {
+	config, err := load([]string{"-translation-cache-path="}, lookupEnvMock(nil))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "", config.Cache.Translation.Path)
+}
+
+func TestLoadInvalidEnvironmentVariable(t *testing.T) {
+	config, err := load([]string{}, lookupEnvMock(map[string]string{EnvPrefix + "POKEAPI_RESPONSE_TIMEOUT": "ten seconds"}))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.EqualValues(t,
+		`invalid value for environment variable SHAKESPEARE_POKEMON_POKEAPI_RESPONSE_TIMEOUT: time: invalid duration "ten seconds"`,
+		err.Error())
+}
+
+func TestLoadInvalidFlag(t *testing.T) {
+	config, err := load([]string{"-pokemon-cache-max-entries", "many"}, lookupEnvMock(nil))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, `invalid value for flag -pokemon-cache-max-entries: strconv.Atoi: parsing "many": invalid syntax`, err.Error())
+}
+
+func TestLoadUnknownFileField(t *testing.T) {
+	path := writeConfigFile(t, `{"server": {"port": 8080}}`)
+	defer os.Remove(path)
+
+	config, err := load([]string{"-config", path}, lookupEnvMock(nil))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `json: unknown field "port"`)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	config, err := load([]string{"-config", "does-not-exist.json"}, lookupEnvMock(nil))
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "error when reading the configuration file")
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Server.Address = ""
+	config.Server.Mode = "verbose"
+	config.PokeAPI.BaseUrl = "pokeapi.co"
+	config.PokeAPI.ConnectTimeout = Duration{time.Minute}
+	config.Translation.ResponseTimeout = Duration{}
+	config.Batch.MaxItems = config.RateLimit.Limit + 1
+
+	err := config.Validate()
+	assert.NotNil(t, err)
+	assert.EqualValues(t, "invalid configuration: server.address cannot be empty; "+
+		"server.mode must be one of debug, release or test; "+
+		"pokeapi.base_url must be an absolute http or https url; "+
+		"pokeapi.connect_timeout cannot exceed pokeapi.response_timeout; "+
+		"translation.response_timeout must be positive; "+
+		"translation.connect_timeout cannot exceed translation.response_timeout; "+
+		"batch.max_items cannot exceed rate_limit.limit, a full batch would never be allowed", err.Error())
+}
+
+func TestDurationJSON(t *testing.T) {
+	bytes, err := json.Marshal(Duration{90 * time.Second})
+	assert.Nil(t, err)
+	assert.EqualValues(t, `"1m30s"`, string(bytes))
+
+	var duration Duration
+	assert.Nil(t, json.Unmarshal(bytes, &duration))
+	assert.EqualValues(t, 90*time.Second, duration.Duration)
+	assert.NotNil(t, json.Unmarshal([]byte("90"), &duration))
+}
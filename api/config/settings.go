@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Duration is a time.Duration read from strings such as "300ms" or "5m" in json, environment variables and flags
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+//setting is a single configuration value which can be overridden by an environment variable and a command line flag,
+//the environment variable name is derived from the flag name, e.g. -pokeapi-url and SHAKESPEARE_POKEMON_POKEAPI_URL
+type setting struct {
+	name  string
+	usage string
+	apply func(config *Config, value string) error
+}
+
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(s.name, "-", "_", -1))
+}
+
+var (
+	settings = []setting{
+		stringSetting("address", "address the server listens on, e.g. :8080", func(c *Config) *string { return &c.Server.Address }),
+		stringSetting("mode", "gin mode, one of debug, release or test", func(c *Config) *string { return &c.Server.Mode }),
+		durationSetting("shutdown-timeout", "time given to the in-flight requests to complete on shutdown", func(c *Config) *Duration { return &c.Server.ShutdownTimeout }),
+		stringSetting("pokeapi-url", "base url of the PokeAPI", func(c *Config) *string { return &c.PokeAPI.BaseUrl }),
+		durationSetting("pokeapi-connect-timeout", "time spent connecting to the PokeAPI", func(c *Config) *Duration { return &c.PokeAPI.ConnectTimeout }),
+		durationSetting("pokeapi-response-timeout", "time spent waiting for a PokeAPI response", func(c *Config) *Duration { return &c.PokeAPI.ResponseTimeout }),
+		intSetting("pokeapi-max-attempts", "maximum number of attempts of a PokeAPI call, 1 disables the retries", func(c *Config) *int { return &c.PokeAPI.Retry.MaxAttempts }),
+		durationSetting("pokeapi-initial-backoff", "time waited before retrying a PokeAPI call the first time", func(c *Config) *Duration { return &c.PokeAPI.Retry.InitialBackoff }),
+		durationSetting("pokeapi-max-backoff", "maximum time waited before retrying a PokeAPI call", func(c *Config) *Duration { return &c.PokeAPI.Retry.MaxBackoff }),
+		intSetting("pokeapi-failure-threshold", "consecutive PokeAPI failures opening its circuit breaker", func(c *Config) *int { return &c.PokeAPI.CircuitBreaker.FailureThreshold }),
+		durationSetting("pokeapi-cool-down", "time the PokeAPI circuit breaker stays open", func(c *Config) *Duration { return &c.PokeAPI.CircuitBreaker.CoolDown }),
+		stringSetting("translation-url", "base url of the FunTranslations API", func(c *Config) *string { return &c.Translation.BaseUrl }),
+		durationSetting("translation-connect-timeout", "time spent connecting to the FunTranslations API", func(c *Config) *Duration { return &c.Translation.ConnectTimeout }),
+		durationSetting("translation-response-timeout", "time spent waiting for a FunTranslations API response", func(c *Config) *Duration { return &c.Translation.ResponseTimeout }),
+		intSetting("translation-max-attempts", "maximum number of attempts of a FunTranslations API call, 1 disables the retries, every retry spends the quota uncounted", func(c *Config) *int { return &c.Translation.Retry.MaxAttempts }),
+		durationSetting("translation-initial-backoff", "time waited before retrying a FunTranslations API call the first time", func(c *Config) *Duration { return &c.Translation.Retry.InitialBackoff }),
+		durationSetting("translation-max-backoff", "maximum time waited before retrying a FunTranslations API call", func(c *Config) *Duration { return &c.Translation.Retry.MaxBackoff }),
+		intSetting("translation-failure-threshold", "consecutive FunTranslations API failures opening its circuit breaker", func(c *Config) *int { return &c.Translation.CircuitBreaker.FailureThreshold }),
+		durationSetting("translation-cool-down", "time the FunTranslations API circuit breaker stays open", func(c *Config) *Duration { return &c.Translation.CircuitBreaker.CoolDown }),
+		intSetting("translation-quota-limit", "FunTranslations API calls allowed per quota window", func(c *Config) *int { return &c.Translation.Quota.Limit }),
+		durationSetting("translation-quota-window", "window of the FunTranslations API quota", func(c *Config) *Duration { return &c.Translation.Quota.Window }),
+		durationSetting("translation-quota-max-wait", "time a FunTranslations API call waits for the quota before being rejected", func(c *Config) *Duration { return &c.Translation.Quota.MaxWait }),
+		intSetting("rate-limit", "requests allowed per client and rate limit window", func(c *Config) *int { return &c.RateLimit.Limit }),
+		durationSetting("rate-limit-window", "window of the per client rate limit", func(c *Config) *Duration { return &c.RateLimit.Window }),
+		intSetting("rate-limit-max-clients", "maximum number of clients whose rate limit is tracked", func(c *Config) *int { return &c.RateLimit.MaxClients }),
+		stringListSetting("rate-limit-api-keys", "comma separated api keys whose clients have their own rate limit", func(c *Config) *[]string { return &c.RateLimit.APIKeys }),
+		stringListSetting("rate-limit-trusted-proxies", "comma separated ips or CIDR networks of the proxies trusted to set X-Forwarded-For", func(c *Config) *[]string { return &c.RateLimit.TrustedProxies }),
+		intSetting("batch-max-items", "maximum number of pokemon of a batch request, each counting against the rate limit", func(c *Config) *int { return &c.Batch.MaxItems }),
+		intSetting("batch-workers", "pokemon of all the batch requests translated concurrently", func(c *Config) *int { return &c.Batch.Workers }),
+		stringListSetting("translation-fallback", "comma separated translation engines tried in order, among funtranslations, cache, local and original", func(c *Config) *[]string { return &c.Translation.Fallback }),
+		stringSetting("log-level", "minimum level of the logs, one of debug, info, warn or error", func(c *Config) *string { return &c.Log.Level }),
+		stringSetting("log-path", "file the logs are appended to, empty to write them to the standard output", func(c *Config) *string { return &c.Log.Path }),
+		stringSetting("translation-cache-path", "file the translation cache is persisted to, empty to keep it in memory", func(c *Config) *string { return &c.Cache.Translation.Path }),
+		durationSetting("translation-cache-ttl", "time a translation is cached for", func(c *Config) *Duration { return &c.Cache.Translation.TTL }),
+		durationSetting("translation-cache-stale-ttl", "time an expired translation can still be served when nothing else can translate", func(c *Config) *Duration { return &c.Cache.Translation.StaleTTL }),
+		intSetting("translation-cache-max-entries", "maximum number of cached translations", func(c *Config) *int { return &c.Cache.Translation.MaxEntries }),
+		durationSetting("pokemon-cache-ttl", "time the pokemon information is cached for", func(c *Config) *Duration { return &c.Cache.Pokemon.TTL }),
+		durationSetting("pokemon-cache-negative-ttl", "time a pokemon which was not found is remembered for", func(c *Config) *Duration { return &c.Cache.Pokemon.NegativeTTL }),
+		intSetting("pokemon-cache-max-entries", "maximum number of cached pokemon", func(c *Config) *int { return &c.Cache.Pokemon.MaxEntries }),
+	}
+
+	settingsByName = func() map[string]setting {
+		byName := make(map[string]setting, len(settings))
+		for _, s := range settings {
+			byName[s.name] = s
+		}
+		return byName
+	}()
+)
+
+func stringSetting(name string, usage string, field func(*Config) *string) setting {
+	return setting{
+		name:  name,
+		usage: usage,
+		apply: func(config *Config, value string) error {
+			*field(config) = value
+			return nil
+		},
+	}
+}
+
+//stringListSetting reads a comma separated list, an empty value clears the list
+func stringListSetting(name string, usage string, field func(*Config) *[]string) setting {
+	return setting{
+		name:  name,
+		usage: usage,
+		apply: func(config *Config, value string) error {
+			if strings.TrimSpace(value) == "" {
+				*field(config) = []string{}
+				return nil
+			}
+			values := strings.Split(value, ",")
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+			*field(config) = values
+			return nil
+		},
+	}
+}
+
+func durationSetting(name string, usage string, field func(*Config) *Duration) setting {
+	return setting{
+		name:  name,
+		usage: usage,
+		apply: func(config *Config, value string) error {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			field(config).Duration = parsed
+			return nil
+		},
+	}
+}
+
+func intSetting(name string, usage string, field func(*Config) *int) setting {
+	return setting{
+		name:  name,
+		usage: usage,
+		apply: func(config *Config, value string) error {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			*field(config) = parsed
+			return nil
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package error_response
+
+import (
+	"github.com/gin-gonic/gin"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/logger"
+	"shakespearing-pokemon/api/utils/accept"
+	"strconv"
+)
+
+//Write answers with the {"error": {...}} envelope, or with an RFC 7807 problem details document when the client
+//prefers application/problem+json. The Retry-After header is set when the error tells when to retry
+func Write(c *gin.Context, apiError *api_error.Error) {
+	if retryAfter := apiError.RetryAfter(); retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	c.Header("Vary", "Accept")
+	if !prefersProblem(c.GetHeader("Accept")) {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+
+	//the content type is only set by the JSON renderer when missing
+	c.Header("Content-Type", api_error.ProblemContentType)
+	c.JSON(apiError.Status(), apiError.Problem(c.Request.URL.RequestURI(), logger.RequestIDFromContext(c.Request.Context())))
+}
+
+//prefersProblem tells whether the Accept header lists application/problem+json with a quality at least as high as the
+//one of application/json. Wildcards are left out, the envelope is kept for the clients which did not ask for problems
+func prefersProblem(header string) bool {
+	preferences := accept.Parse(header)
+	problemQuality := accept.Quality(preferences, api_error.ProblemContentType)
+	return problemQuality > 0 && problemQuality >= accept.Quality(preferences, "application/json")
+}
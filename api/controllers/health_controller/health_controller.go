@@ -0,0 +1,33 @@
+package health_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"shakespearing-pokemon/api/services"
+)
+
+//Controller serves the probes from the health service
+type Controller struct {
+	healthService services.HealthServiceInterface
+}
+
+//New creates a controller serving the probes from the given health service
+func New(healthService services.HealthServiceInterface) *Controller {
+	return &Controller{healthService: healthService}
+}
+
+//HandleLivenessRequest reports that the process is alive, it does not depend on any external api
+func (h *Controller) HandleLivenessRequest(c *gin.Context) {
+	c.JSON(http.StatusOK, h.healthService.GetLiveness())
+}
+
+//HandleReadinessRequest reports whether the external apis are reachable, 503 is returned if any of them is not
+func (h *Controller) HandleReadinessRequest(c *gin.Context) {
+	readiness := h.healthService.GetReadiness()
+	if !readiness.Ready() {
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	c.JSON(http.StatusOK, readiness)
+}
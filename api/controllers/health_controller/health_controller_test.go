@@ -0,0 +1,83 @@
+package health_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"shakespearing-pokemon/api/domains/health/health_domain"
+	"testing"
+)
+
+type healthServiceMock struct {
+	getReadinessFunc func() *health_domain.ReadinessResponse
+}
+
+func (h *healthServiceMock) GetLiveness() *health_domain.LivenessResponse {
+	return &health_domain.LivenessResponse{Status: health_domain.StatusUp}
+}
+
+func (h *healthServiceMock) GetReadiness() *health_domain.ReadinessResponse {
+	return h.getReadinessFunc()
+}
+
+func TestHandleLivenessRequest(t *testing.T) {
+	controller := New(&healthServiceMock{})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/healthz", nil)
+	controller.HandleLivenessRequest(c)
+
+	var actualResponse health_domain.LivenessResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, health_domain.StatusUp, actualResponse.Status)
+}
+
+func TestHandleReadinessRequest(t *testing.T) {
+	expectedResponse := health_domain.ReadinessResponse{
+		Status: health_domain.StatusUp,
+		Dependencies: []health_domain.DependencyStatus{
+			{Name: "pokeapi", Status: health_domain.StatusUp, LatencyMs: 20},
+			{Name: "funtranslations", Status: health_domain.StatusUp, LatencyMs: 30},
+		},
+	}
+	getReadinessFunc := func() *health_domain.ReadinessResponse {
+		return &expectedResponse
+	}
+	controller := New(&healthServiceMock{getReadinessFunc: getReadinessFunc})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/readyz", nil)
+	controller.HandleReadinessRequest(c)
+
+	var actualResponse health_domain.ReadinessResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleReadinessRequestNotReady(t *testing.T) {
+	getReadinessFunc := func() *health_domain.ReadinessResponse {
+		return &health_domain.ReadinessResponse{
+			Status: health_domain.StatusDown,
+			Dependencies: []health_domain.DependencyStatus{
+				{Name: "pokeapi", Status: health_domain.StatusUp, LatencyMs: 20},
+				{Name: "funtranslations", Status: health_domain.StatusDown, LatencyMs: 30, Error: "connection refused"},
+			},
+		}
+	}
+	controller := New(&healthServiceMock{getReadinessFunc: getReadinessFunc})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/readyz", nil)
+	controller.HandleReadinessRequest(c)
+
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.Code)
+}
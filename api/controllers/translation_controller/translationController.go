@@ -3,20 +3,73 @@ package translation_controller
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"shakespearing-pokemon/api/controllers/error_response"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
 	"shakespearing-pokemon/api/services"
+	"shakespearing-pokemon/api/utils/language"
 )
 
-func HandleShakespeareanPokemonTranslationRequest(c *gin.Context) {
+//Controller serves the translations of the pokemon descriptions from the translation and batch services
+type Controller struct {
+	translationService services.TranslationServiceInterface
+	batchService       services.BatchServiceInterface
+}
+
+//New creates a controller serving the translations from the given services
+func New(translationService services.TranslationServiceInterface, batchService services.BatchServiceInterface) *Controller {
+	return &Controller{translationService: translationService, batchService: batchService}
+}
+
+//HandleShakespeareanPokemonTranslationRequest translates the description of a pokemon to the style given in the path
+//or the style query parameter, Shakespearean English by default. The version query parameter selects the game version
+//of the description, the latest by default, and its language is picked from the lang query parameter, then the
+//Accept-Language header
+func (t *Controller) HandleShakespeareanPokemonTranslationRequest(c *gin.Context) {
 	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{
-		Name: c.Param("pokemonName"),
+		Name:      c.Param("pokemonName"),
+		Style:     c.Param("style"),
+		Version:   c.Query("version"),
+		Languages: languages(c),
+	}
+	if request.Style == "" {
+		request.Style = c.Query("style")
+	}
+
+	response, apiError := t.translationService.GetShakespeareanPokemonTranslation(c.Request.Context(), request)
+	if apiError != nil {
+		error_response.Write(c, apiError)
+		return
 	}
 
-	response, apiError := services.TranslationService.GetShakespeareanPokemonTranslation(request)
+	c.JSON(http.StatusOK, response)
+}
+
+//HandleShakespeareanPokemonBatchRequest translates the descriptions of the pokemon listed in the request body, each of
+//them with its own style and version. The languages are picked as for a single pokemon and a pokemon which could not be
+//translated does not fail the others
+func (t *Controller) HandleShakespeareanPokemonBatchRequest(c *gin.Context) {
+	var request shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		error_response.Write(c, api_error.Wrap(err, http.StatusBadRequest, api_error.CodeInvalidRequest, "invalid batch request: "+err.Error()))
+		return
+	}
+	request.Languages = languages(c)
+
+	response, apiError := t.batchService.GetShakespeareanPokemonTranslations(c.Request.Context(), request)
 	if apiError != nil {
-		c.JSON(apiError.Status(), apiError)
+		error_response.Write(c, apiError)
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+//languages lists the languages preferred by the client, the lang query parameter first, then the Accept-Language header
+func languages(c *gin.Context) []string {
+	var languages []string
+	if lang := c.Query("lang"); lang != "" {
+		languages = append(languages, lang)
+	}
+	return append(languages, language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+}
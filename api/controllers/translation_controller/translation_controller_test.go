@@ -1,26 +1,41 @@
 package translation_controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"shakespearing-pokemon/api/caches/translation_cache"
+	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
-	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_error"
+	"shakespearing-pokemon/api/logger"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/providers/pokemon_provider"
+	"shakespearing-pokemon/api/providers/translation_provider"
 	"shakespearing-pokemon/api/services"
+	"strings"
 	"testing"
 )
 
-var (
-	getShakespeareanPokemonTranslationFunc func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, shksprean_pokemon_error.ShkspreanPokemonErrorInterface)
-)
+type translationServiceMock struct {
+	getShakespeareanPokemonTranslationFunc func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error)
+}
 
-type translationServiceMock struct{}
+func (t *translationServiceMock) GetShakespeareanPokemonTranslation(ctx context.Context, request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+	return t.getShakespeareanPokemonTranslationFunc(request)
+}
+
+type batchServiceMock struct {
+	getShakespeareanPokemonTranslationsFunc func(request shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse, *api_error.Error)
+}
 
-func (t *translationServiceMock) GetShakespeareanPokemonTranslation(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, shksprean_pokemon_error.ShkspreanPokemonErrorInterface) {
-	return getShakespeareanPokemonTranslationFunc(request)
+func (b *batchServiceMock) GetShakespeareanPokemonTranslations(ctx context.Context, request shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse, *api_error.Error) {
+	return b.getShakespeareanPokemonTranslationsFunc(request)
 }
 
 func TestGetShakespeareanPokemonTranslationSuccess(t *testing.T) {
@@ -29,11 +44,11 @@ func TestGetShakespeareanPokemonTranslationSuccess(t *testing.T) {
 		Translation: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
 	}
 
-	getShakespeareanPokemonTranslationFunc = func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, shksprean_pokemon_error.ShkspreanPokemonErrorInterface) {
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
 		return &expectedTranslation, nil
 	}
 
-	services.TranslationService = &translationServiceMock{}
+	controller := New(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, &batchServiceMock{})
 
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
@@ -41,7 +56,7 @@ func TestGetShakespeareanPokemonTranslationSuccess(t *testing.T) {
 	c.Params = gin.Params{
 		{Key: "name", Value: "charizard"},
 	}
-	HandleShakespeareanPokemonTranslationRequest(c)
+	controller.HandleShakespeareanPokemonTranslationRequest(c)
 	var actualResponse shksprean_pokemon_domain.ShakespeareanPokemonResponse
 	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
 	assert.Nil(t, err)
@@ -52,11 +67,11 @@ func TestGetShakespeareanPokemonTranslationSuccess(t *testing.T) {
 func TestGetShakespeareanPokemonTranslationInvalidName(t *testing.T) {
 	expectedError := fmt.Sprintf("wrong name")
 
-	getShakespeareanPokemonTranslationFunc = func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, shksprean_pokemon_error.ShkspreanPokemonErrorInterface) {
-		return nil, shksprean_pokemon_error.New(http.StatusInternalServerError, expectedError)
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		return nil, api_error.New(http.StatusInternalServerError, api_error.CodeUpstreamError, expectedError)
 	}
 
-	services.TranslationService = &translationServiceMock{}
+	controller := New(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, &batchServiceMock{})
 
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
@@ -64,34 +79,226 @@ func TestGetShakespeareanPokemonTranslationInvalidName(t *testing.T) {
 	c.Params = gin.Params{
 		{Key: "name", Value: "invalid name"},
 	}
-	HandleShakespeareanPokemonTranslationRequest(c)
+	controller.HandleShakespeareanPokemonTranslationRequest(c)
 	assert.EqualValues(t, http.StatusInternalServerError, response.Code)
-	apiErr, err := shksprean_pokemon_error.NewApiErrorFromBytes(response.Body.Bytes())
+	apiErr, err := api_error.NewApiErrorFromBytes(response.Body.Bytes())
 	assert.Nil(t, err)
 	assert.NotNil(t, apiErr)
 	assert.EqualValues(t, http.StatusInternalServerError, apiErr.Status())
 	assert.EqualValues(t, expectedError, apiErr.Message())
 }
 
+func TestGetShakespeareanPokemonTranslationRetryAfter(t *testing.T) {
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		return nil, api_error.New(http.StatusServiceUnavailable, api_error.CodeUpstreamUnavailable, "the FunTranslations API is unavailable, circuit breaker is open").WithRetryAfter(30)
+	}
+
+	controller := New(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, &batchServiceMock{})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "", nil)
+	c.Params = gin.Params{
+		{Key: "pokemonName", Value: "charizard"},
+	}
+	controller.HandleShakespeareanPokemonTranslationRequest(c)
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.Code)
+	assert.EqualValues(t, "30", response.Header().Get("Retry-After"))
+	apiErr, err := api_error.NewApiErrorFromBytes(response.Body.Bytes())
+	assert.Nil(t, err)
+	assert.EqualValues(t, 30, apiErr.RetryAfter())
+}
+
+func TestGetShakespeareanPokemonTranslationProblem(t *testing.T) {
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		return nil, api_error.New(http.StatusBadGateway, api_error.CodeUpstreamError, "error from external api").WithUpstream("funtranslations")
+	}
+
+	controller := New(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, &batchServiceMock{})
+
+	tests := []struct {
+		accept          string
+		expectedProblem bool
+	}{
+		{accept: "", expectedProblem: false},
+		{accept: "*/*", expectedProblem: false},
+		{accept: "application/json", expectedProblem: false},
+		{accept: "application/problem+json", expectedProblem: true},
+		{accept: "application/json, application/problem+json", expectedProblem: true},
+		{accept: "application/problem+json;q=0.5, application/json", expectedProblem: false},
+		{accept: "application/problem+json;q=0, */*", expectedProblem: false},
+	}
+	for _, test := range tests {
+		response := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(response)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/pokemon/charizard?style=yoda", nil)
+		c.Request.Header.Set("Accept", test.accept)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), "f3b1c2"))
+		c.Params = gin.Params{
+			{Key: "pokemonName", Value: "charizard"},
+		}
+		controller.HandleShakespeareanPokemonTranslationRequest(c)
+		assert.EqualValues(t, http.StatusBadGateway, response.Code, test.accept)
+		assert.EqualValues(t, "Accept", response.Header().Get("Vary"), test.accept)
+
+		if !test.expectedProblem {
+			assert.True(t, strings.HasPrefix(response.Header().Get("Content-Type"), "application/json"), test.accept)
+			apiErr, err := api_error.NewApiErrorFromBytes(response.Body.Bytes())
+			assert.Nil(t, err, test.accept)
+			assert.EqualValues(t, api_error.CodeUpstreamError, apiErr.Code(), test.accept)
+			continue
+		}
+
+		assert.EqualValues(t, api_error.ProblemContentType, response.Header().Get("Content-Type"), test.accept)
+		var problem api_error.Problem
+		assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &problem), test.accept)
+		assert.EqualValues(t, api_error.Problem{
+			Type:      "urn:shakespearing-pokemon:problem:upstream-error",
+			Title:     "Bad Gateway",
+			Status:    http.StatusBadGateway,
+			Detail:    "error from external api",
+			Instance:  "/pokemon/charizard?style=yoda",
+			Code:      api_error.CodeUpstreamError,
+			Upstream:  "funtranslations",
+			RequestID: "f3b1c2",
+		}, problem, test.accept)
+	}
+}
+
+func TestGetShakespeareanPokemonTranslationStyle(t *testing.T) {
+	var styles, versions []string
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		styles = append(styles, request.Style)
+		versions = append(versions, request.Version)
+		return &shksprean_pokemon_domain.ShakespeareanPokemonResponse{Name: request.Name, Style: request.Style}, nil
+	}
+
+	controller := New(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, &batchServiceMock{})
+
+	testCases := []struct {
+		target string
+		params gin.Params
+	}{
+		{target: "/pokemon/charizard/yoda", params: gin.Params{{Key: "pokemonName", Value: "charizard"}, {Key: "style", Value: "yoda"}}},
+		{target: "/pokemon/charizard?style=pirate&version=red", params: gin.Params{{Key: "pokemonName", Value: "charizard"}}},
+		{target: "/pokemon/charizard", params: gin.Params{{Key: "pokemonName", Value: "charizard"}}},
+	}
+	for _, testCase := range testCases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest(http.MethodGet, testCase.target, nil)
+		c.Params = testCase.params
+		controller.HandleShakespeareanPokemonTranslationRequest(c)
+	}
+
+	//the service falls back to the default style
+	assert.EqualValues(t, []string{"yoda", "pirate", ""}, styles)
+	assert.EqualValues(t, []string{"", "red", ""}, versions)
+}
+
+func TestGetShakespeareanPokemonTranslationLanguages(t *testing.T) {
+	var languages []string
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		languages = request.Languages
+		return &shksprean_pokemon_domain.ShakespeareanPokemonResponse{Name: request.Name}, nil
+	}
+
+	controller := New(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, &batchServiceMock{})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodGet, "/pokemon/charizard?lang=ja", nil)
+	c.Request.Header.Set("Accept-Language", "de;q=0.5, fr-CH")
+	c.Params = gin.Params{{Key: "pokemonName", Value: "charizard"}}
+	controller.HandleShakespeareanPokemonTranslationRequest(c)
+
+	//the lang query parameter comes first
+	assert.EqualValues(t, []string{"ja", "fr-CH", "de"}, languages)
+}
+
+func TestGetShakespeareanPokemonTranslationsBatch(t *testing.T) {
+	var actualRequest shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest
+	getShakespeareanPokemonTranslationsFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse, *api_error.Error) {
+		actualRequest = request
+		return &shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse{
+			Results: []shksprean_pokemon_domain.ShakespeareanPokemonBatchResult{
+				{Name: "charizard", Status: http.StatusOK, Response: &shksprean_pokemon_domain.ShakespeareanPokemonResponse{Name: "charizard"}},
+				{Name: "missingno", Status: http.StatusNotFound, Error: &api_error.Fields{Code: api_error.CodePokemonNotFound, Status: http.StatusNotFound, Message: "not found"}},
+			},
+		}, nil
+	}
+
+	controller := New(&translationServiceMock{}, &batchServiceMock{getShakespeareanPokemonTranslationsFunc: getShakespeareanPokemonTranslationsFunc})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/pokemon/batch?lang=fr",
+		strings.NewReader(`{"pokemon": [{"name": "charizard", "style": "yoda"}, {"name": "missingno", "version": "red"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	controller.HandleShakespeareanPokemonBatchRequest(c)
+
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{
+		Pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{
+			{Name: "charizard", Style: "yoda"},
+			{Name: "missingno", Version: "red"},
+		},
+		Languages: []string{"fr"},
+	}, actualRequest)
+	var actualResponse shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, len(actualResponse.Results))
+	assert.EqualValues(t, api_error.CodePokemonNotFound, actualResponse.Results[1].Error.Code)
+}
+
+func TestGetShakespeareanPokemonTranslationsInvalidBatch(t *testing.T) {
+	controller := New(&translationServiceMock{}, &batchServiceMock{})
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/pokemon/batch", strings.NewReader(`{"pokemon": "charizard"}`))
+	controller.HandleShakespeareanPokemonBatchRequest(c)
+
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	apiErr, err := api_error.NewApiErrorFromBytes(response.Body.Bytes())
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, apiErr.Status())
+	assert.EqualValues(t, api_error.CodeInvalidRequest, apiErr.Code())
+	assert.True(t, strings.HasPrefix(apiErr.Message(), "invalid batch request: "), apiErr.Message())
+}
+
 func TestGetShakespeareanPokemonTranslationSuccessSuccessIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	expectedTranslation := shksprean_pokemon_domain.ShakespeareanPokemonResponse{
-		Name:        "charizard",
-		Translation: "charizard flies 'round the sky in search of powerful opponents.'t breathes fire of such most wondrous heat yond 't melts aught. However,  't nev'r turns its fiery breath on any opponentweaker than itself.",
-	}
+	client := restclient.New()
+	cfg := config.Default()
+	//the translations are cached in memory only
+	translationCache, err := translation_cache.New(translation_cache.Config{
+		TTL:        cfg.Cache.Translation.TTL.Duration,
+		StaleTTL:   cfg.Cache.Translation.StaleTTL.Duration,
+		MaxEntries: cfg.Cache.Translation.MaxEntries,
+	})
+	assert.Nil(t, err)
+	translationService := services.NewTranslationService(pokemon_provider.New(client, cfg.PokeAPI, metrics.NewRegistry()),
+		services.NewTranslationChain(cfg.Translation.Fallback, translation_provider.New(client, cfg.Translation, metrics.NewRegistry()),
+			translation_cache.NewStaleProvider(translationCache)))
+	controller := New(translationService, services.NewBatchService(translationService, cfg.Batch))
 
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
-	c.Request, _ = http.NewRequest(http.MethodGet, "", nil)
+	c.Request, _ = http.NewRequest(http.MethodGet, "?version=red", nil)
 	c.Params = gin.Params{
 		{Key: "pokemonName", Value: "charizard"},
 	}
-	HandleShakespeareanPokemonTranslationRequest(c)
+	controller.HandleShakespeareanPokemonTranslationRequest(c)
 	var actualResponse shksprean_pokemon_domain.ShakespeareanPokemonResponse
-	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
 	assert.Nil(t, err)
 	assert.EqualValues(t, http.StatusOK, response.Code)
-	assert.EqualValues(t, expectedTranslation, actualResponse)
+	//the translation depends on the live APIs, and on the quota left, only its stable fields are checked
+	assert.EqualValues(t, "charizard", actualResponse.Name)
+	assert.EqualValues(t, "red", actualResponse.Version)
+	assert.EqualValues(t, "shakespeare", actualResponse.Style)
+	assert.EqualValues(t, "en", actualResponse.Language)
+	assert.NotEmpty(t, actualResponse.Translation)
 }
@@ -0,0 +1,150 @@
+package api_error
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+//Code tells apart the errors of the API whatever their message, clients are expected to rely on it rather than on the
+//status code, several errors sharing the same status
+type Code string
+
+const (
+	//CodeInvalidRequest tells that the request is malformed, e.g. its pokemon name is empty
+	CodeInvalidRequest Code = "INVALID_REQUEST"
+	//CodeUnknownStyle tells that the requested translation style is not supported
+	CodeUnknownStyle Code = "UNKNOWN_STYLE"
+	//CodeUnsupportedLanguage tells that the translator cannot translate a description written in its language
+	CodeUnsupportedLanguage Code = "UNSUPPORTED_LANGUAGE"
+	//CodePokemonNotFound tells that the PokeAPI does not know the pokemon
+	CodePokemonNotFound Code = "POKEMON_NOT_FOUND"
+	//CodeDescriptionUnavailable tells that the pokemon exists but has no description in the language, it is nothing the
+	//client can fix by retrying
+	CodeDescriptionUnavailable Code = "DESCRIPTION_UNAVAILABLE"
+	//CodeVersionUnavailable tells that the pokemon has descriptions, but none for the requested game version
+	CodeVersionUnavailable Code = "VERSION_UNAVAILABLE"
+	//CodeTranslationUnavailable tells that a translator has no translation of the text, e.g. it is not cached
+	CodeTranslationUnavailable Code = "TRANSLATION_UNAVAILABLE"
+	//CodeRateLimited tells that the client sent too many requests
+	CodeRateLimited Code = "RATE_LIMITED"
+	//CodeRequestCanceled tells that the client abandoned the request before it completed
+	CodeRequestCanceled Code = "REQUEST_CANCELED"
+	//CodeTranslationQuotaExceeded tells that the FunTranslations quota is spent
+	CodeTranslationQuotaExceeded Code = "TRANSLATION_QUOTA_EXCEEDED"
+	//CodeUpstreamRateLimited tells that an external api rejected a call because too many were made
+	CodeUpstreamRateLimited Code = "UPSTREAM_RATE_LIMITED"
+	//CodeUpstreamUnavailable tells that an external api is considered down, its circuit breaker is open
+	CodeUpstreamUnavailable Code = "UPSTREAM_UNAVAILABLE"
+	//CodeUpstreamTimeout tells that an external api did not respond in time
+	CodeUpstreamTimeout Code = "UPSTREAM_TIMEOUT"
+	//CodeUpstreamError tells that an external api could not be reached or responded with an unexpected status
+	CodeUpstreamError Code = "UPSTREAM_ERROR"
+	//CodeUpstreamBadResponse tells that an external api responded with a body which could not be decoded
+	CodeUpstreamBadResponse Code = "UPSTREAM_BAD_RESPONSE"
+)
+
+//Error is the error returned by every layer of the API, from the providers to the controllers. Its message is sent to
+//the client while its cause, if any, is only logged and can be inspected with errors.Is and errors.As
+type Error struct {
+	fields Fields
+	cause  error
+	//upstream names the external api the error comes from, empty if the API itself failed the request
+	upstream string
+}
+
+//Fields are the fields of an error sent to the client, wrapped in the {"error": {...}} envelope
+type Fields struct {
+	Code    Code   `json:"code"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	//RetryAfter hints in seconds when the request is worth retrying, zero if it is unknown
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+type envelope struct {
+	Error Fields `json:"error"`
+}
+
+//New creates an error answered with the given status code
+func New(status int, code Code, message string) *Error {
+	return &Error{fields: Fields{Code: code, Status: status, Message: message}}
+}
+
+//Wrap creates an error caused by err, the cause is only part of the message sent to the client if the message quotes it
+func Wrap(err error, status int, code Code, message string) *Error {
+	return &Error{fields: Fields{Code: code, Status: status, Message: message}, cause: err}
+}
+
+//WithRetryAfter returns a copy of the error telling the client to retry the request after the given number of seconds
+func (e *Error) WithRetryAfter(retryAfter int) *Error {
+	copied := *e
+	copied.fields.RetryAfter = retryAfter
+	return &copied
+}
+
+//WithUpstream returns a copy of the error telling it comes from the given external api
+func (e *Error) WithUpstream(upstream string) *Error {
+	copied := *e
+	copied.upstream = upstream
+	return &copied
+}
+
+func (e *Error) Error() string {
+	return string(e.fields.Code) + ": " + e.fields.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) Code() Code {
+	return e.fields.Code
+}
+
+func (e *Error) Status() int {
+	return e.fields.Status
+}
+
+func (e *Error) Message() string {
+	return e.fields.Message
+}
+
+func (e *Error) RetryAfter() int {
+	return e.fields.RetryAfter
+}
+
+func (e *Error) Upstream() string {
+	return e.upstream
+}
+
+//Fields returns the fields of the error sent to the client, e.g. to report the error of a pokemon within a batch
+func (e *Error) Fields() Fields {
+	return e.fields
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(envelope{Error: e.fields})
+}
+
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var result envelope
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	e.fields = result.Error
+	return nil
+}
+
+//HasCode tells whether err is, or wraps, an API error of the given code
+func HasCode(err error, code Code) bool {
+	var apiError *Error
+	return errors.As(err, &apiError) && apiError.Code() == code
+}
+
+func NewApiErrorFromBytes(body []byte) (*Error, error) {
+	var result Error
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
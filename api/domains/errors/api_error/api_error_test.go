@@ -0,0 +1,91 @@
+package api_error
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	actualError := New(404, CodePokemonNotFound, "pokemon not found")
+	assert.EqualValues(t, 404, actualError.Status())
+	assert.EqualValues(t, CodePokemonNotFound, actualError.Code())
+	assert.EqualValues(t, "pokemon not found", actualError.Message())
+	assert.EqualValues(t, 0, actualError.RetryAfter())
+	assert.Nil(t, actualError.Unwrap())
+	assert.EqualValues(t, "POKEMON_NOT_FOUND: pokemon not found", actualError.Error())
+}
+
+func TestWithRetryAfter(t *testing.T) {
+	original := New(503, CodeUpstreamUnavailable, "the PokeAPI is unavailable, circuit breaker is open")
+	actualError := original.WithRetryAfter(30)
+	assert.EqualValues(t, 30, actualError.RetryAfter())
+	assert.EqualValues(t, 0, original.RetryAfter())
+
+	bytes, err := json.Marshal(actualError)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"error":{"code":"UPSTREAM_UNAVAILABLE","status":503,"message":"the PokeAPI is unavailable, circuit breaker is open","retry_after":30}}`, string(bytes))
+}
+
+func TestWrap(t *testing.T) {
+	actualError := Wrap(context.DeadlineExceeded, 504, CodeUpstreamTimeout, "error when trying to get pokemon info results")
+	assert.EqualValues(t, "error when trying to get pokemon info results", actualError.Message())
+	assert.EqualValues(t, "UPSTREAM_TIMEOUT: error when trying to get pokemon info results", actualError.Error())
+	assert.True(t, errors.Is(actualError, context.DeadlineExceeded))
+	assert.False(t, errors.Is(actualError, context.Canceled))
+
+	//the cause is not sent to the client
+	bytes, err := json.Marshal(actualError)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"error":{"code":"UPSTREAM_TIMEOUT","status":504,"message":"error when trying to get pokemon info results"}}`, string(bytes))
+}
+
+func TestHasCode(t *testing.T) {
+	wrapped := fmt.Errorf("translating charizard: %w", New(429, CodeTranslationQuotaExceeded, "the FunTranslations API quota is spent"))
+
+	var apiError *Error
+	assert.True(t, errors.As(wrapped, &apiError))
+	assert.EqualValues(t, 429, apiError.Status())
+	assert.True(t, HasCode(wrapped, CodeTranslationQuotaExceeded))
+	assert.False(t, HasCode(wrapped, CodeRateLimited))
+	assert.False(t, HasCode(errors.New("quota spent"), CodeTranslationQuotaExceeded))
+}
+
+func TestNewApiErrorFromBytes(t *testing.T) {
+	expectedError := New(400, CodeInvalidRequest, "name field cannot be empty").WithRetryAfter(10)
+
+	bytes, err := json.Marshal(expectedError)
+	assert.Nil(t, err)
+
+	actualError, err := NewApiErrorFromBytes(bytes)
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedError.Fields(), actualError.Fields())
+
+	actualError, err = NewApiErrorFromBytes([]byte(`{"error": "not an error"}`))
+	assert.Nil(t, actualError)
+	assert.NotNil(t, err)
+}
+
+func TestProblem(t *testing.T) {
+	apiError := New(503, CodeUpstreamUnavailable, "the PokeAPI is unavailable, circuit breaker is open").
+		WithRetryAfter(30).WithUpstream("pokeapi")
+
+	bytes, err := json.Marshal(apiError.Problem("/pokemon/charizard", "f3b1c2"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"type":"urn:shakespearing-pokemon:problem:upstream-unavailable","title":"Service Unavailable",`+
+		`"status":503,"detail":"the PokeAPI is unavailable, circuit breaker is open","instance":"/pokemon/charizard",`+
+		`"code":"UPSTREAM_UNAVAILABLE","upstream":"pokeapi","request_id":"f3b1c2","retry_after":30}`, string(bytes))
+
+	//the upstream is not part of the default envelope
+	bytes, err = json.Marshal(apiError)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(bytes), "pokeapi")
+
+	problem := New(400, CodeInvalidRequest, "name field cannot be empty").Problem("", "")
+	assert.EqualValues(t, "Bad Request", problem.Title)
+	assert.EqualValues(t, "", problem.Upstream)
+	assert.EqualValues(t, "Client Closed Request", New(StatusClientClosedRequest, CodeRequestCanceled, "canceled").Problem("", "").Title)
+}
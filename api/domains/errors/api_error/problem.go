@@ -0,0 +1,52 @@
+package api_error
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	//ProblemContentType is the media type of the RFC 7807 problem details documents
+	ProblemContentType = "application/problem+json"
+	//problemTypePrefix prefixes the code of the error to form the type of the problem, a URN since the problem types
+	//are not documented online
+	problemTypePrefix = "urn:shakespearing-pokemon:problem:"
+)
+
+//Problem is the RFC 7807 rendering of an error, the members after Instance are extensions of the standard ones
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+	Code     Code   `json:"code"`
+	//Upstream names the external api which failed the request, if any
+	Upstream   string `json:"upstream,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+//Problem renders the error as a problem details document, instance is the URI of the request which failed
+func (e *Error) Problem(instance string, requestID string) Problem {
+	return Problem{
+		Type:       problemTypePrefix + strings.ToLower(strings.Replace(string(e.fields.Code), "_", "-", -1)),
+		Title:      problemTitle(e.fields.Status),
+		Status:     e.fields.Status,
+		Detail:     e.fields.Message,
+		Instance:   instance,
+		Code:       e.fields.Code,
+		Upstream:   e.upstream,
+		RequestID:  requestID,
+		RetryAfter: e.fields.RetryAfter,
+	}
+}
+
+//problemTitle summarizes the problem by its status, every code has a single status hence the title does not change
+//from one occurrence of the problem to another, as the RFC asks
+func problemTitle(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
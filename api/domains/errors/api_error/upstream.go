@@ -0,0 +1,44 @@
+package api_error
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	//StatusClientClosedRequest is the status of a request abandoned by its client before it completed, the client is
+	//gone hence the status only shows in the logs and metrics
+	StatusClientClosedRequest = 499
+)
+
+//NewUpstreamError classifies an error returned when calling the upstream external api: timeouts are gateway timeouts, calls
+//abandoned by the client are reported as such and any other error, e.g. a refused connection, is a bad gateway
+func NewUpstreamError(upstream string, err error, message string) *Error {
+	var timeout interface{ Timeout() bool }
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeout) && timeout.Timeout()):
+		return Wrap(err, http.StatusGatewayTimeout, CodeUpstreamTimeout, message).WithUpstream(upstream)
+	case errors.Is(err, context.Canceled):
+		return Wrap(err, StatusClientClosedRequest, CodeRequestCanceled, message).WithUpstream(upstream)
+	}
+	return Wrap(err, http.StatusBadGateway, CodeUpstreamError, message).WithUpstream(upstream)
+}
+
+//NewUpstreamStatusError classifies an unexpected status code returned by an external api. A 429 is passed through with
+//the Retry-After the external api gave, so are the outages and timeouts the api reports itself, while any other status
+//is a bad gateway: the request of the client is valid, the call made on its behalf was not
+func NewUpstreamStatusError(upstream string, status int, retryAfter int, message string) *Error {
+	var apiError *Error
+	switch status {
+	case http.StatusTooManyRequests:
+		apiError = New(http.StatusTooManyRequests, CodeUpstreamRateLimited, message).WithRetryAfter(retryAfter)
+	case http.StatusServiceUnavailable:
+		apiError = New(http.StatusServiceUnavailable, CodeUpstreamUnavailable, message).WithRetryAfter(retryAfter)
+	case http.StatusGatewayTimeout:
+		apiError = New(http.StatusGatewayTimeout, CodeUpstreamTimeout, message)
+	default:
+		apiError = New(http.StatusBadGateway, CodeUpstreamError, message)
+	}
+	return apiError.WithUpstream(upstream)
+}
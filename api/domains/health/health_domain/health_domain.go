@@ -0,0 +1,55 @@
+package health_domain
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+//Used to report that the process is alive in the form of:
+//	{
+//		"status": "up"
+//	}
+type LivenessResponse struct {
+	Status string `json:"status"`
+}
+
+//Used to report whether the api and the apis it depends on are ready to process requests in the form of:
+//	{
+//		"status": "up",
+//		"dependencies": [
+//			{
+//				"name": "pokeapi",
+//				"status": "up",
+//				"latency_ms": 112,
+//				"circuit_breaker": "closed"
+//			},
+//			{
+//				"name": "funtranslations",
+//				"status": "down",
+//				"optional": true,
+//				"latency_ms": 0,
+//				"circuit_breaker": "open",
+//				"error": "circuit breaker is open"
+//			}
+//		]
+//	}
+type ReadinessResponse struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+type DependencyStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	//Optional tells that the service stays ready while the dependency is down
+	Optional  bool  `json:"optional,omitempty"`
+	LatencyMs int64 `json:"latency_ms"`
+	//CircuitBreaker is the state of the circuit breaker guarding the dependency, empty if there is none
+	CircuitBreaker string `json:"circuit_breaker,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+//Ready reports whether every dependency which is not optional is up
+func (r ReadinessResponse) Ready() bool {
+	return r.Status == StatusUp
+}
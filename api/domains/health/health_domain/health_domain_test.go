@@ -0,0 +1,27 @@
+package health_domain
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReadinessResponse(t *testing.T) {
+	expectedResponse := ReadinessResponse{
+		Status: StatusDown,
+		Dependencies: []DependencyStatus{
+			{Name: "pokeapi", Status: StatusUp, LatencyMs: 112},
+			{Name: "funtranslations", Status: StatusDown, LatencyMs: 10001, Error: "context deadline exceeded"},
+		},
+	}
+
+	bytes, err := json.Marshal(expectedResponse)
+	assert.Nil(t, err)
+	assert.NotNil(t, bytes)
+
+	var actualResponse ReadinessResponse
+	err = json.Unmarshal(bytes, &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+	assert.False(t, actualResponse.Ready())
+}
@@ -1,5 +1,18 @@
 package pokemon_domain
 
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	//ErrDescriptionUnavailable tells that a pokemon has no description in the requested language
+	ErrDescriptionUnavailable = errors.New("description unavailable")
+	//ErrVersionUnavailable tells that a pokemon has descriptions in the requested language, but none for the requested
+	//game version
+	ErrVersionUnavailable = errors.New("version unavailable")
+)
+
 type PokemonInfoRequest struct {
 	Name string
 }
@@ -13,6 +26,10 @@ type PokemonInfoRequest struct {
 //					"name": "en",
 //					"url": "https://pokeapi.co/api/v2/language/9/"
 //				},
+//				"version": {
+//					"name": "red",
+//					"url": "https://pokeapi.co/api/v2/version/1/"
+//				}
 //			},
 //			{
 //				"flavor_text": "Spits fire that\nis hot enough to\nmelt boulders.\fKnown to cause\nforest fires\nunintentionally.",
@@ -20,6 +37,10 @@ type PokemonInfoRequest struct {
 //					"name": "en",
 //					"url": "https://pokeapi.co/api/v2/language/9/"
 //				},
+//				"version": {
+//					"name": "blue",
+//					"url": "https://pokeapi.co/api/v2/version/2/"
+//				}
 //			}
 //		],
 //		"name": "charizard",
@@ -29,13 +50,57 @@ type PokemonInfoResponse struct {
 	Description FlavourTextList `json:"flavor_text_entries"`
 }
 
+//FlavourTextList holds the descriptions of a pokemon, the PokeAPI lists them from the earliest to the latest version
 type FlavourTextList []FlavourText
 
 type FlavourText struct {
 	Text     string `json:"flavor_text"`
 	Language LanguageFields
+	Version  VersionFields `json:"version"`
 }
 
 type LanguageFields struct {
 	Name string `json:"name"`
 }
+
+type VersionFields struct {
+	Name string `json:"name"`
+}
+
+//InLanguage returns the descriptions written in the language, in the same order, blank descriptions are left out
+func (l FlavourTextList) InLanguage(language string) FlavourTextList {
+	var entries FlavourTextList
+	for _, entry := range l {
+		if entry.Language.Name == language && strings.TrimSpace(entry.Text) != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+//Languages returns the names of the languages the descriptions are written in, in order of appearance, languages with
+//blank descriptions only are left out
+func (l FlavourTextList) Languages() []string {
+	var languages []string
+	seen := make(map[string]bool, len(l))
+	for _, entry := range l {
+		if entry.Language.Name != "" && !seen[entry.Language.Name] && strings.TrimSpace(entry.Text) != "" {
+			seen[entry.Language.Name] = true
+			languages = append(languages, entry.Language.Name)
+		}
+	}
+	return languages
+}
+
+//Versions returns the names of the versions the descriptions come from, from the earliest to the latest
+func (l FlavourTextList) Versions() []string {
+	var versions []string
+	seen := make(map[string]bool, len(l))
+	for _, entry := range l {
+		if entry.Version.Name != "" && !seen[entry.Version.Name] {
+			seen[entry.Version.Name] = true
+			versions = append(versions, entry.Version.Name)
+		}
+	}
+	return versions
+}
@@ -46,3 +46,23 @@ func TestPokemonInfoResponse(t *testing.T) {
 		assert.EqualValues(t, flavourTextList[textIndex].Language.Name, actualResponse.Description[textIndex].Language.Name)
 	}
 }
+
+func TestFlavourTextList(t *testing.T) {
+	var response PokemonInfoResponse
+	err := json.Unmarshal([]byte(`{"name": "charizard", "flavor_text_entries": [
+		{"flavor_text": "Spits fire.", "language": {"name": "en"}, "version": {"name": "red"}},
+		{"flavor_text": "Crache du feu.", "language": {"name": "fr"}, "version": {"name": "x"}},
+		{"flavor_text": "Spits fire.", "language": {"name": "en"}, "version": {"name": "blue"}},
+		{"flavor_text": "Breathes fire.", "language": {"name": "en"}, "version": {"name": "x"}},
+		{"flavor_text": " \n", "language": {"name": "ja"}, "version": {"name": "x"}}
+	]}`), &response)
+	assert.Nil(t, err)
+
+	english := response.Description.InLanguage("en")
+	assert.EqualValues(t, 3, len(english))
+	assert.EqualValues(t, "Breathes fire.", english[2].Text)
+	assert.EqualValues(t, []string{"red", "blue", "x"}, english.Versions())
+	assert.EqualValues(t, []string{"red", "x", "blue"}, response.Description.Versions())
+	assert.EqualValues(t, 0, len(response.Description.InLanguage("ja")))
+	assert.EqualValues(t, []string{"en", "fr"}, response.Description.Languages())
+}
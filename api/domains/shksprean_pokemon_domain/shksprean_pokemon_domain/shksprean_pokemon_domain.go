@@ -1,15 +1,94 @@
 package shksprean_pokemon_domain
 
+import "shakespearing-pokemon/api/domains/errors/api_error"
+
+const (
+	//EngineFunTranslations, EngineCache, EngineLocal and EngineOriginal tell whether a description was translated by
+	//the FunTranslations API, served from an expired cache entry, translated by the local translator or left untranslated
+	EngineFunTranslations = "funtranslations"
+	EngineCache           = "cache"
+	EngineLocal           = "local"
+	EngineOriginal        = "original"
+)
+
+const (
+	//VersionLatest, VersionEarliest and VersionRandom select the description of the latest, the earliest or a random
+	//game version rather than of a named one, e.g. red
+	VersionLatest   = "latest"
+	VersionEarliest = "earliest"
+	VersionRandom   = "random"
+)
+
+//Engines lists the engines a translation fallback chain can be made of
+var Engines = []string{EngineFunTranslations, EngineCache, EngineLocal, EngineOriginal}
+
+//ShakespeareanPokemonRequest asks for the description of the pokemon Name in the game Version translated to Style,
+//the latest description translated to Shakespearean English when empty. The description is written in the first of
+//the Languages, ordered from the most preferred one, the pokemon has a description in, English otherwise
 type ShakespeareanPokemonRequest struct {
-	Name string
+	Name      string
+	Style     string
+	Version   string
+	Languages []string
 }
 
 //Used to store and generate Shakespearean translation of the pokemon's description in the form of:
 //		{
 //			"name": "charizard",
 //			"description": "translated version of charizard's description",
+//			"style": "shakespeare",
+//			"version": "red",
+//			"language": "en",
+//			"engine": "funtranslations",
+//			"degraded": false
 //		}
+//degraded is true when the description is not an up to date translation of the FunTranslations API
 type ShakespeareanPokemonResponse struct {
 	Name        string `json:"name"`
 	Translation string `json:"description"`
+	Style       string `json:"style"`
+	Version     string `json:"version"`
+	Language    string `json:"language"`
+	Engine      string `json:"engine"`
+	Degraded    bool   `json:"degraded"`
+}
+
+//ShakespeareanPokemonBatchRequest asks for the descriptions of several pokemon at once, each of them with its own style
+//and version, in the form of:
+//		{
+//			"pokemon": [
+//				{"name": "charizard"},
+//				{"name": "pikachu", "style": "yoda", "version": "red"}
+//			]
+//		}
+//the descriptions are all written in the first of the Languages the pokemon has a description in
+type ShakespeareanPokemonBatchRequest struct {
+	Pokemon   []ShakespeareanPokemonBatchItem `json:"pokemon"`
+	Languages []string                        `json:"-"`
+}
+
+type ShakespeareanPokemonBatchItem struct {
+	Name    string `json:"name"`
+	Style   string `json:"style"`
+	Version string `json:"version"`
+}
+
+//ShakespeareanPokemonBatchResponse holds the result of every pokemon of a batch request, in the order of the request,
+//in the form of:
+//		{
+//			"results": [
+//				{"name": "charizard", "status": 200, "response": {"name": "charizard", "description": "..."}},
+//				{"name": "missingno", "status": 404, "error": {"code": "POKEMON_NOT_FOUND", "status": 404, "message": "..."}}
+//			]
+//		}
+//a pokemon which could not be translated does not fail the others
+type ShakespeareanPokemonBatchResponse struct {
+	Results []ShakespeareanPokemonBatchResult `json:"results"`
+}
+
+type ShakespeareanPokemonBatchResult struct {
+	Name     string                        `json:"name"`
+	Status   int                           `json:"status"`
+	Response *ShakespeareanPokemonResponse `json:"response,omitempty"`
+	Error    *api_error.Fields             `json:"error,omitempty"`
 }
@@ -1,7 +1,23 @@
 package translation_domain
 
+const (
+	//DefaultStyle is the style a text is translated to when the request does not ask for another one
+	DefaultStyle = "shakespeare"
+	//DefaultLanguage is the language of a text whose language is not told
+	DefaultLanguage = "en"
+)
+
+//TranslationRequest asks for Text, written in Language, to be translated to Style. The empty language and style stand
+//for DefaultLanguage and DefaultStyle
 type TranslationRequest struct {
-	Text string
+	Text     string
+	Style    string
+	Language string
+}
+
+//IsDefaultLanguage reports whether the text is written in DefaultLanguage
+func (r TranslationRequest) IsDefaultLanguage() bool {
+	return r.Language == "" || r.Language == DefaultLanguage
 }
 
 //Used to parse and store json responses containing the translation in the form of:
@@ -0,0 +1,21 @@
+package logger
+
+import "context"
+
+type contextKey struct{}
+
+//ContextWithRequestID returns a copy of ctx carrying the correlation id of the request
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+//RequestIDFromContext returns the correlation id carried by ctx, empty if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
+
+//FromContext returns a logger derived from the default logger adding the correlation id carried by ctx to every line
+func FromContext(ctx context.Context) *Logger {
+	return std.WithRequestID(RequestIDFromContext(ctx))
+}
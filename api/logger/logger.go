@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//RequestIDHeader is the header used to receive, return and forward the correlation id of a request
+	RequestIDHeader = "X-Request-ID"
+	//RequestIDField is the field every log line related to a request carries its correlation id in
+	RequestIDField = "request_id"
+)
+
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+//ParseLevel converts a level name such as "info" to its Level
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %q, expected one of debug, info, warn or error", name)
+}
+
+//Fields are the key value pairs attached to a log line
+type Fields map[string]interface{}
+
+//Logger writes one json object per line in the form of:
+//	{"time":"2020-10-10T12:00:00Z","level":"error","msg":"pokemon not found","request_id":"4f0c2a...","status":404}
+//Loggers derived with With share the output and level of their parent
+type Logger struct {
+	output *output
+	fields Fields
+}
+
+type output struct {
+	mu     sync.Mutex
+	writer io.Writer
+	level  Level
+	now    func() time.Time
+}
+
+var (
+	std = New(os.Stdout, InfoLevel)
+)
+
+func New(writer io.Writer, level Level) *Logger {
+	return &Logger{output: &output{writer: writer, level: level, now: time.Now}}
+}
+
+//SetOutput changes where the default logger writes to
+func SetOutput(writer io.Writer) {
+	std.output.mu.Lock()
+	defer std.output.mu.Unlock()
+	std.output.writer = writer
+}
+
+//Sync commits the logs written by the default logger to disk when it writes to a file, the standard streams are left
+//alone as they are not buffered
+func Sync() error {
+	std.output.mu.Lock()
+	defer std.output.mu.Unlock()
+	if file, ok := std.output.writer.(*os.File); ok && file != os.Stdout && file != os.Stderr {
+		return file.Sync()
+	}
+	return nil
+}
+
+//SetLevel changes the minimum level written by the default logger
+func SetLevel(level Level) {
+	std.output.mu.Lock()
+	defer std.output.mu.Unlock()
+	std.output.level = level
+}
+
+//With returns a logger adding fields to every line, on top of the fields of the parent
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Logger{output: l.output, fields: merged}
+}
+
+//WithRequestID returns a logger adding the correlation id of a request to every line, an empty id is omitted
+func (l *Logger) WithRequestID(requestID string) *Logger {
+	if requestID == "" {
+		return l
+	}
+	return l.With(Fields{RequestIDField: requestID})
+}
+
+func (l *Logger) Debug(msg string, fields ...Fields) {
+	l.log(DebugLevel, msg, fields)
+}
+
+func (l *Logger) Info(msg string, fields ...Fields) {
+	l.log(InfoLevel, msg, fields)
+}
+
+func (l *Logger) Warn(msg string, fields ...Fields) {
+	l.log(WarnLevel, msg, fields)
+}
+
+func (l *Logger) Error(msg string, fields ...Fields) {
+	l.log(ErrorLevel, msg, fields)
+}
+
+//Fatal logs at the error level and exits the process
+func (l *Logger) Fatal(msg string, fields ...Fields) {
+	l.log(ErrorLevel, msg, fields)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level Level, msg string, extraFields []Fields) {
+	l.output.mu.Lock()
+	defer l.output.mu.Unlock()
+
+	if level < l.output.level {
+		return
+	}
+
+	fields := l.fields
+	if len(extraFields) > 0 {
+		fields = l.With(mergeFields(extraFields)).fields
+	}
+
+	var line bytes.Buffer
+	line.WriteString(`{"time":`)
+	writeValue(&line, l.output.now().UTC().Format(time.RFC3339Nano))
+	line.WriteString(`,"level":`)
+	writeValue(&line, level.String())
+	line.WriteString(`,"msg":`)
+	writeValue(&line, msg)
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		line.WriteString(",")
+		writeValue(&line, key)
+		line.WriteString(":")
+		writeValue(&line, fields[key])
+	}
+	line.WriteString("}\n")
+
+	l.output.writer.Write(line.Bytes())
+}
+
+func mergeFields(fieldsList []Fields) Fields {
+	merged := Fields{}
+	for _, fields := range fieldsList {
+		for key, value := range fields {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+//writeValue writes value as json, errors are written as their message and unsupported values as strings
+func writeValue(line *bytes.Buffer, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(value))
+	}
+	line.Write(encoded)
+}
+
+//With returns a logger derived from the default logger
+func With(fields Fields) *Logger {
+	return std.With(fields)
+}
+
+//WithRequestID returns a logger derived from the default logger adding the correlation id of a request to every line
+func WithRequestID(requestID string) *Logger {
+	return std.WithRequestID(requestID)
+}
+
+func Debug(msg string, fields ...Fields) {
+	std.log(DebugLevel, msg, fields)
+}
+
+func Info(msg string, fields ...Fields) {
+	std.log(InfoLevel, msg, fields)
+}
+
+func Warn(msg string, fields ...Fields) {
+	std.log(WarnLevel, msg, fields)
+}
+
+func Error(msg string, fields ...Fields) {
+	std.log(ErrorLevel, msg, fields)
+}
+
+//Fatal logs at the error level with the default logger and exits the process
+func Fatal(msg string, fields ...Fields) {
+	std.log(ErrorLevel, msg, fields)
+	os.Exit(1)
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestLogger(level Level) (*Logger, *bytes.Buffer) {
+	var buffer bytes.Buffer
+	l := New(&buffer, level)
+	l.output.now = func() time.Time { return time.Date(2020, time.October, 10, 12, 0, 0, 0, time.UTC) }
+	return l, &buffer
+}
+
+func TestLog(t *testing.T) {
+	l, buffer := newTestLogger(InfoLevel)
+
+	l.WithRequestID("4f0c2a").Error("error from external api", Fields{"status": 500, "error": errors.New("EOF")})
+	assert.EqualValues(t,
+		`{"time":"2020-10-10T12:00:00Z","level":"error","msg":"error from external api","error":"EOF","request_id":"4f0c2a","status":500}`+"\n",
+		buffer.String())
+}
+
+func TestLogBelowLevel(t *testing.T) {
+	l, buffer := newTestLogger(WarnLevel)
+
+	l.Debug("debug")
+	l.Info("info")
+	assert.EqualValues(t, "", buffer.String())
+
+	l.Warn("warn")
+	assert.Contains(t, buffer.String(), `"level":"warn"`)
+}
+
+func TestWithDoesNotChangeParent(t *testing.T) {
+	l, buffer := newTestLogger(InfoLevel)
+
+	l.With(Fields{"provider": "pokemon_provider"}).WithRequestID("").Info("child")
+	l.Info("parent")
+	assert.EqualValues(t,
+		`{"time":"2020-10-10T12:00:00Z","level":"info","msg":"child","provider":"pokemon_provider"}`+"\n"+
+			`{"time":"2020-10-10T12:00:00Z","level":"info","msg":"parent"}`+"\n",
+		buffer.String())
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	assert.Nil(t, err)
+	assert.EqualValues(t, WarnLevel, level)
+
+	_, err = ParseLevel("verbose")
+	assert.NotNil(t, err)
+	assert.EqualValues(t, `unknown log level "verbose", expected one of debug, info, warn or error`, err.Error())
+}
+
+func TestContextWithRequestID(t *testing.T) {
+	ctx := ContextWithRequestID(context.Background(), "4f0c2a")
+	assert.EqualValues(t, "4f0c2a", RequestIDFromContext(ctx))
+	assert.EqualValues(t, "", RequestIDFromContext(context.Background()))
+	assert.EqualValues(t, Fields{RequestIDField: "4f0c2a"}, FromContext(ctx).fields)
+}
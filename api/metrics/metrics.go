@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"shakespearing-pokemon/api/caches/lru"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"strconv"
+	"time"
+)
+
+const (
+	//StatusNetworkError is used as upstream status when no response was received from the external api
+	StatusNetworkError = "network_error"
+)
+
+//series are the application metrics, registered to every registry created by NewRegistry
+type series struct {
+	httpRequests              *CounterVec
+	httpRequestDuration       *HistogramVec
+	upstreamRequests          *CounterVec
+	upstreamRequestDuration   *HistogramVec
+	translationQuotaRemaining *GaugeVec
+}
+
+func (r *Registry) registerSeries() {
+	r.httpRequests = r.NewCounterVec("http_requests_total",
+		"Total number of HTTP requests processed, partitioned by method, route and status code.",
+		"method", "route", "status")
+	r.httpRequestDuration = r.NewHistogramVec("http_request_duration_seconds",
+		"Latency of the HTTP requests processed, partitioned by method, route and status code.",
+		DefaultBuckets, "method", "route", "status")
+	r.upstreamRequests = r.NewCounterVec("upstream_requests_total",
+		"Total number of calls to the external apis, partitioned by provider and status code.",
+		"provider", "status")
+	r.upstreamRequestDuration = r.NewHistogramVec("upstream_request_duration_seconds",
+		"Latency of the calls to the external apis, partitioned by provider and status code.",
+		DefaultBuckets, "provider", "status")
+	r.translationQuotaRemaining = r.NewGaugeVec("translation_quota_remaining",
+		"Remaining FunTranslations calls in the current window, as reported by the api.")
+}
+
+//ObserveHttpRequest records a request processed by the router
+func (r *Registry) ObserveHttpRequest(method string, route string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	r.httpRequests.Inc(method, route, statusLabel)
+	r.httpRequestDuration.Observe(duration.Seconds(), method, route, statusLabel)
+}
+
+//ObserveUpstreamRequest records a call to an external api, a nil response means no response was received
+func (r *Registry) ObserveUpstreamRequest(provider string, response *http.Response, duration time.Duration) {
+	statusLabel := StatusNetworkError
+	if response != nil {
+		statusLabel = strconv.Itoa(response.StatusCode)
+	}
+	r.upstreamRequests.Inc(provider, statusLabel)
+	r.upstreamRequestDuration.Observe(duration.Seconds(), provider, statusLabel)
+}
+
+//SetTranslationQuotaRemaining records the number of translations which can still be requested
+func (r *Registry) SetTranslationQuotaRemaining(remaining int) {
+	r.translationQuotaRemaining.Set(float64(remaining))
+}
+
+//RegisterCache exposes the hit, miss and eviction counters and the size of a cache
+func (r *Registry) RegisterCache(name string, stats func() lru.Stats) {
+	labels := map[string]string{"cache": name}
+	r.NewCounterFunc("cache_hits_total", "Total number of cache hits.", labels,
+		func() float64 { return float64(stats().Hits) })
+	r.NewCounterFunc("cache_misses_total", "Total number of cache misses.", labels,
+		func() float64 { return float64(stats().Misses) })
+	r.NewCounterFunc("cache_evictions_total", "Total number of entries evicted to keep the cache bounded.", labels,
+		func() float64 { return float64(stats().Evictions) })
+	r.NewGaugeFunc("cache_entries", "Number of entries currently cached.", labels,
+		func() float64 { return float64(stats().Entries) })
+}
+
+//RegisterTranslationQuota exposes the translation calls the local rate limiter still allows
+func (r *Registry) RegisterTranslationQuota(status func() ratelimit.Status) {
+	r.NewGaugeFunc("translation_quota_budget", "Translation calls the local rate limiter still allows in the current window.",
+		nil, func() float64 { return float64(status().Remaining) })
+}
+
+//RegisterCircuitBreaker exposes the state of the circuit breaker guarding an external api
+func (r *Registry) RegisterCircuitBreaker(upstream string, state func() circuitbreaker.State) {
+	r.NewGaugeFunc("circuit_breaker_state", "State of the circuit breaker guarding an external api, 0 closed, 1 open and 2 half open.",
+		map[string]string{"upstream": upstream}, func() float64 { return float64(state()) })
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"shakespearing-pokemon/api/caches/lru"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := newRegistry()
+	requests := registry.NewCounterVec("requests_total", "Total requests.", "provider", "status")
+	quota := registry.NewGaugeVec("quota_remaining", "Remaining quota.")
+	latency := registry.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "provider")
+	registry.NewGaugeFunc("entries", "Cached entries.", map[string]string{"cache": "pokemon"}, func() float64 { return 3 })
+	registry.NewGaugeFunc("entries", "Cached entries.", map[string]string{"cache": "translation"}, func() float64 { return 7 })
+
+	requests.Inc("translation_provider", "429")
+	requests.Inc("pokemon_provider", "404")
+	requests.Add(2, "pokemon_provider", "404")
+	quota.Set(4)
+	latency.Observe(0.05, `say "hi"`)
+	latency.Observe(0.5, `say "hi"`)
+
+	var buffer bytes.Buffer
+	err := registry.WriteText(&buffer)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `# HELP entries Cached entries.
+# TYPE entries gauge
+entries{cache="pokemon"} 3
+entries{cache="translation"} 7
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{provider="say \"hi\"",le="0.1"} 1
+latency_seconds_bucket{provider="say \"hi\"",le="1"} 2
+latency_seconds_bucket{provider="say \"hi\"",le="+Inf"} 2
+latency_seconds_sum{provider="say \"hi\""} 0.55
+latency_seconds_count{provider="say \"hi\""} 2
+# HELP quota_remaining Remaining quota.
+# TYPE quota_remaining gauge
+quota_remaining 4
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{provider="pokemon_provider",status="404"} 3
+requests_total{provider="translation_provider",status="429"} 1
+`, buffer.String())
+}
+
+func TestWrongNumberOfLabels(t *testing.T) {
+	registry := newRegistry()
+	requests := registry.NewCounterVec("requests_total", "Total requests.", "provider", "status")
+	assert.Panics(t, func() { requests.Inc("pokemon_provider") })
+}
+
+func TestObserveUpstreamRequest(t *testing.T) {
+	registry := NewRegistry()
+	registry.ObserveUpstreamRequest("pokemon_provider", &http.Response{StatusCode: http.StatusNotFound}, 10*time.Millisecond)
+	registry.ObserveUpstreamRequest("translation_provider", nil, time.Second)
+	registry.SetTranslationQuotaRemaining(2)
+	registry.RegisterCache("test", func() lru.Stats { return lru.Stats{Hits: 5, Misses: 2, Entries: 1} })
+	registry.RegisterCircuitBreaker("test", func() circuitbreaker.State { return circuitbreaker.Open })
+	registry.RegisterTranslationQuota(func() ratelimit.Status { return ratelimit.Status{Limit: 5, Remaining: 4} })
+
+	response := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := response.Body.String()
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "text/plain; version=0.0.4; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Contains(t, body, `upstream_requests_total{provider="pokemon_provider",status="404"} 1`)
+	assert.Contains(t, body, `upstream_requests_total{provider="translation_provider",status="network_error"} 1`)
+	assert.Contains(t, body, `upstream_request_duration_seconds_count{provider="pokemon_provider",status="404"} 1`)
+	assert.Contains(t, body, "translation_quota_remaining 2")
+	assert.Contains(t, body, `cache_hits_total{cache="test"} 5`)
+	assert.Contains(t, body, `circuit_breaker_state{upstream="test"} 1`)
+	assert.Contains(t, body, "translation_quota_budget 4")
+	assert.Contains(t, body, `cache_misses_total{cache="test"} 2`)
+	assert.Contains(t, body, `cache_entries{cache="test"} 1`)
+}
+
+func TestRegistriesAreIndependent(t *testing.T) {
+	first := NewRegistry()
+	second := NewRegistry()
+	first.ObserveHttpRequest(http.MethodGet, "/pokemon/:name", http.StatusOK, time.Millisecond)
+	first.RegisterCache("pokemon", func() lru.Stats { return lru.Stats{} })
+
+	var buffer bytes.Buffer
+	assert.Nil(t, second.WriteText(&buffer))
+	assert.NotContains(t, buffer.String(), "http_requests_total{")
+	assert.NotContains(t, buffer.String(), "cache_entries")
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+
+	//contentType is the version 0.0.4 of the Prometheus text exposition format
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+//Registry holds the metrics of an application instance and renders them in the Prometheus text exposition format
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+	series
+}
+
+//family groups every sample sharing the same metric name
+type family struct {
+	name       string
+	help       string
+	kind       string
+	collectors []func() []sample
+}
+
+type sample struct {
+	suffix string
+	labels []labelPair
+	value  float64
+}
+
+type labelPair struct {
+	name  string
+	value string
+}
+
+//NewRegistry creates a registry holding the application metrics
+func NewRegistry() *Registry {
+	r := newRegistry()
+	r.registerSeries()
+	return r
+}
+
+func newRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+//register adds a collector to the family called name, creating the family if it does not exist yet
+func (r *Registry) register(name string, help string, kind string, collect func() []sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, kind: kind}
+		r.families[name] = f
+	}
+	f.collectors = append(f.collectors, collect)
+}
+
+//WriteText writes every family sorted by name in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	writer := bufio.NewWriter(w)
+	for _, f := range families {
+		writer.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		writer.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+		for _, collect := range f.collectors {
+			for _, s := range collect() {
+				writer.WriteString(f.name + s.suffix + formatLabels(s.labels) + " " + formatValue(s.value) + "\n")
+			}
+		}
+	}
+	return writer.Flush()
+}
+
+//Handler serves the metrics of the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		r.WriteText(w)
+	})
+}
+
+func formatLabels(labels []labelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, label.name+`="`+escapeLabelValue(label.value)+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
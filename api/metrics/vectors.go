@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	//DefaultBuckets are the upper bounds, in seconds, of the latency histograms
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+//vector stores one value per combination of label values
+type vector struct {
+	mu         sync.Mutex
+	labelNames []string
+	entries    map[string]*vectorEntry
+}
+
+type vectorEntry struct {
+	labels []labelPair
+	value  interface{}
+}
+
+func newVector(labelNames []string) *vector {
+	return &vector{labelNames: labelNames, entries: make(map[string]*vectorEntry)}
+}
+
+//with returns the value stored for labelValues, locked until the returned function is called
+func (v *vector) with(labelValues []string, newValue func() interface{}) (interface{}, func()) {
+	if len(labelValues) != len(v.labelNames) {
+		panic("metrics: expected " + strings.Join(v.labelNames, ",") + " label values")
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	entry, ok := v.entries[key]
+	if !ok {
+		labels := make([]labelPair, len(labelValues))
+		for i, value := range labelValues {
+			labels[i] = labelPair{name: v.labelNames[i], value: value}
+		}
+		entry = &vectorEntry{labels: labels, value: newValue()}
+		v.entries[key] = entry
+	}
+	return entry.value, v.mu.Unlock
+}
+
+//sorted returns the entries sorted by label values so that the output is stable
+func (v *vector) sorted() []*vectorEntry {
+	keys := make([]string, 0, len(v.entries))
+	for key := range v.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]*vectorEntry, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, v.entries[key])
+	}
+	return entries
+}
+
+//CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	*vector
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{newVector(labelNames)}
+	r.register(name, help, counterType, c.collect)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	value, unlock := c.with(labelValues, func() interface{} { return new(float64) })
+	defer unlock()
+	*value.(*float64) += delta
+}
+
+func (c *CounterVec) collect() []sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	samples := make([]sample, 0, len(c.entries))
+	for _, entry := range c.sorted() {
+		samples = append(samples, sample{labels: entry.labels, value: *entry.value.(*float64)})
+	}
+	return samples
+}
+
+//GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	*vector
+}
+
+func (r *Registry) NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{newVector(labelNames)}
+	r.register(name, help, gaugeType, g.collect)
+	return g
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	current, unlock := g.with(labelValues, func() interface{} { return new(float64) })
+	defer unlock()
+	*current.(*float64) = value
+}
+
+func (g *GaugeVec) collect() []sample {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	samples := make([]sample, 0, len(g.entries))
+	for _, entry := range g.sorted() {
+		samples = append(samples, sample{labels: entry.labels, value: *entry.value.(*float64)})
+	}
+	return samples
+}
+
+//HistogramVec counts observations in buckets, partitioned by labels
+type HistogramVec struct {
+	*vector
+	buckets []float64
+}
+
+type histogramValue struct {
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{vector: newVector(labelNames), buckets: buckets}
+	r.register(name, help, histogramType, h.collect)
+	return h
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	current, unlock := h.with(labelValues, func() interface{} {
+		return &histogramValue{bucketCounts: make([]uint64, len(h.buckets))}
+	})
+	defer unlock()
+
+	histogram := current.(*histogramValue)
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			histogram.bucketCounts[i]++
+		}
+	}
+	histogram.sum += value
+	histogram.count++
+}
+
+func (h *HistogramVec) collect() []sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var samples []sample
+	for _, entry := range h.sorted() {
+		histogram := entry.value.(*histogramValue)
+		for i, upperBound := range h.buckets {
+			samples = append(samples, sample{
+				suffix: "_bucket",
+				labels: withLabel(entry.labels, "le", formatValue(upperBound)),
+				value:  float64(histogram.bucketCounts[i]),
+			})
+		}
+		samples = append(samples,
+			sample{suffix: "_bucket", labels: withLabel(entry.labels, "le", formatValue(math.Inf(1))), value: float64(histogram.count)},
+			sample{suffix: "_sum", labels: entry.labels, value: histogram.sum},
+			sample{suffix: "_count", labels: entry.labels, value: float64(histogram.count)},
+		)
+	}
+	return samples
+}
+
+func withLabel(labels []labelPair, name string, value string) []labelPair {
+	extended := make([]labelPair, len(labels), len(labels)+1)
+	copy(extended, labels)
+	return append(extended, labelPair{name: name, value: value})
+}
+
+//NewCounterFunc exposes a counter maintained elsewhere, read from fn on every collection
+func (r *Registry) NewCounterFunc(name string, help string, labels map[string]string, fn func() float64) {
+	r.register(name, help, counterType, funcCollector(labels, fn))
+}
+
+//NewGaugeFunc exposes a gauge maintained elsewhere, read from fn on every collection
+func (r *Registry) NewGaugeFunc(name string, help string, labels map[string]string, fn func() float64) {
+	r.register(name, help, gaugeType, funcCollector(labels, fn))
+}
+
+func funcCollector(labels map[string]string, fn func() float64) func() []sample {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]labelPair, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, labelPair{name: name, value: labels[name]})
+	}
+
+	return func() []sample {
+		return []sample{{labels: pairs, value: fn()}}
+	}
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"shakespearing-pokemon/api/logger"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger.SetOutput(&buffer)
+	defer logger.SetOutput(os.Stdout)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Logger())
+	router.GET("/pokemon/:pokemonName", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/pokemon/missingno", nil)
+	request.Header.Set(logger.RequestIDHeader, "4f0c2a")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	var line map[string]interface{}
+	err := json.Unmarshal(buffer.Bytes(), &line)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "info", line["level"])
+	assert.EqualValues(t, "request processed", line["msg"])
+	assert.EqualValues(t, "4f0c2a", line["request_id"])
+	assert.EqualValues(t, "/pokemon/missingno", line["path"])
+	assert.EqualValues(t, http.StatusNotFound, line["status"])
+}
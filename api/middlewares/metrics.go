@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"shakespearing-pokemon/api/metrics"
+	"time"
+)
+
+const (
+	//unmatchedRoute labels the requests which did not match any route, so that random paths do not create new series
+	unmatchedRoute = "unmatched"
+)
+
+//Metrics records the count and latency of every request per route and status code to the registry
+func Metrics(registry *metrics.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		registry.ObserveHttpRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"shakespearing-pokemon/api/metrics"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := metrics.NewRegistry()
+	router := gin.New()
+	router.Use(Metrics(registry))
+	router.GET("/pokemon/:pokemonName", func(c *gin.Context) {
+		c.String(http.StatusNotFound, "pokemon not found")
+	})
+
+	for _, path := range []string{"/pokemon/missingno", "/pokemon/missingno", "/random/path"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	response := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, response.Body.String(), `http_requests_total{method="GET",route="/pokemon/:pokemonName",status="404"} 2`)
+	assert.Contains(t, response.Body.String(), `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, response.Body.String(), `http_request_duration_seconds_count{method="GET",route="/pokemon/:pokemonName",status="404"} 2`)
+}
@@ -0,0 +1,143 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"shakespearing-pokemon/api/caches/lru"
+	"shakespearing-pokemon/api/controllers/error_response"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//APIKeyHeader identifies the client, the client ip is used when it is missing or unknown
+	APIKeyHeader = "X-API-Key"
+
+	forwardedForHeader       = "X-Forwarded-For"
+	rateLimitLimitHeader     = "X-RateLimit-Limit"
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+)
+
+//RateLimitConfig allows every client Limit requests per Window, the limits of up to MaxClients clients are tracked.
+//Only the APIKeys are trusted to identify a client and only the TrustedProxies are trusted to tell the ip of a client
+//through the X-Forwarded-For header, otherwise a client could get a new limit by changing either header
+type RateLimitConfig struct {
+	ratelimit.Config
+	MaxClients     int
+	APIKeys        []string
+	TrustedProxies []*net.IPNet
+}
+
+//RateLimit allows every client config.Limit requests per config.Window, a client being identified by its api key or
+//its ip. The limits of up to config.MaxClients clients are tracked, the least recently seen ones are forgotten first.
+//Every response tells the limit and the requests left, a rejected request gets a 429 telling when to retry. A handler
+//doing the work of several requests charges the client for them with ratelimit.Charge
+func RateLimit(config RateLimitConfig) gin.HandlerFunc {
+	var mu sync.Mutex
+	//an idle client earns its whole budget back within a window, hence its limiter can be forgotten after that
+	limiters := lru.New(config.MaxClients, config.Window)
+	apiKeys := make(map[string]bool, len(config.APIKeys))
+	for _, apiKey := range config.APIKeys {
+		apiKeys[apiKey] = true
+	}
+
+	limiter := func(client string) *ratelimit.Limiter {
+		mu.Lock()
+		defer mu.Unlock()
+
+		l, ok := limiters.Get(client)
+		if !ok {
+			l = ratelimit.New(config.Config)
+		}
+		limiters.Set(client, l)
+		return l.(*ratelimit.Limiter)
+	}
+
+	return func(c *gin.Context) {
+		l := limiter(clientKey(c.Request, apiKeys, config.TrustedProxies))
+		charge := func(n int) (time.Duration, bool) {
+			wait, allowed := l.AllowN(n)
+			c.Header(rateLimitRemainingHeader, strconv.Itoa(l.Status().Remaining))
+			return wait, allowed
+		}
+
+		c.Header(rateLimitLimitHeader, strconv.Itoa(config.Limit))
+		wait, allowed := charge(1)
+		if !allowed {
+			error_response.Write(c, api_error.New(http.StatusTooManyRequests, api_error.CodeRateLimited, "too many requests, slow down").
+				WithRetryAfter(ratelimit.RetryAfterSeconds(wait)))
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(ratelimit.WithCharge(c.Request.Context(), charge))
+		c.Next()
+	}
+}
+
+func clientKey(request *http.Request, apiKeys map[string]bool, trustedProxies []*net.IPNet) string {
+	if apiKey := request.Header.Get(APIKeyHeader); apiKeys[apiKey] {
+		return "key:" + apiKey
+	}
+	return "ip:" + clientIP(request, trustedProxies)
+}
+
+//clientIP returns the address of the peer of the connection unless it is a trusted proxy, in which case the
+//X-Forwarded-For header is read from the right, the ip of the client being the first one which is not a trusted proxy
+func clientIP(request *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !trusted(ip, trustedProxies) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(request.Header[forwardedForHeader], ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !trusted(hop, trustedProxies) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func trusted(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+//ParseNetworks parses ips and CIDR networks, an ip standing for the network made of that ip only
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,159 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"testing"
+	"time"
+)
+
+//newRateLimitedRouter allows 2 requests per hour, the 4f0c2a api key is known and the proxies of 10.0.0.0/8 trusted
+func newRateLimitedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	trustedProxies, _ := ParseNetworks([]string{"10.0.0.0/8"})
+	router := gin.New()
+	router.GET("/pokemon/:pokemonName", RateLimit(RateLimitConfig{
+		Config:         ratelimit.Config{Limit: 2, Window: time.Hour},
+		MaxClients:     10,
+		APIKeys:        []string{"4f0c2a"},
+		TrustedProxies: trustedProxies,
+	}), func(c *gin.Context) {
+		c.String(http.StatusOK, "charizard")
+	})
+	return router
+}
+
+func rateLimitedRequest(router *gin.Engine, apiKey string, remoteAddr string) *httptest.ResponseRecorder {
+	return forwardedRequest(router, apiKey, remoteAddr, "")
+}
+
+func forwardedRequest(router *gin.Engine, apiKey string, remoteAddr string, forwardedFor string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/pokemon/charizard", nil)
+	request.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		request.Header.Set(APIKeyHeader, apiKey)
+	}
+	if forwardedFor != "" {
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func TestRateLimit(t *testing.T) {
+	router := newRateLimitedRouter()
+
+	for _, remaining := range []string{"1", "0"} {
+		response := rateLimitedRequest(router, "", "192.0.2.1:1234")
+		assert.EqualValues(t, http.StatusOK, response.Code)
+		assert.EqualValues(t, "2", response.Header().Get("X-RateLimit-Limit"))
+		assert.EqualValues(t, remaining, response.Header().Get("X-RateLimit-Remaining"))
+		assert.EqualValues(t, "", response.Header().Get("Retry-After"))
+	}
+
+	response := rateLimitedRequest(router, "", "192.0.2.1:1234")
+	assert.EqualValues(t, http.StatusTooManyRequests, response.Code)
+	assert.EqualValues(t, "2", response.Header().Get("X-RateLimit-Limit"))
+	assert.EqualValues(t, "0", response.Header().Get("X-RateLimit-Remaining"))
+	//a token comes back every half an hour
+	assert.EqualValues(t, "1800", response.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":{"code":"RATE_LIMITED","status":429,"message":"too many requests, slow down","retry_after":1800}}`, response.Body.String())
+}
+
+func TestRateLimitCharge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/pokemon/batch", RateLimit(RateLimitConfig{
+		Config:     ratelimit.Config{Limit: 5, Window: time.Hour},
+		MaxClients: 10,
+	}), func(c *gin.Context) {
+		if _, ok := ratelimit.Charge(c.Request.Context(), 3); !ok {
+			c.Status(http.StatusTooManyRequests)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	//the request itself and the 3 calls it charged
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/pokemon/batch", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "1", response.Header().Get("X-RateLimit-Remaining"))
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/pokemon/batch", nil))
+	assert.EqualValues(t, http.StatusTooManyRequests, response.Code)
+	assert.EqualValues(t, "0", response.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestRateLimitPerClient(t *testing.T) {
+	router := newRateLimitedRouter()
+
+	for i := 0; i < 2; i++ {
+		assert.EqualValues(t, http.StatusOK, rateLimitedRequest(router, "", "192.0.2.1:1234").Code)
+	}
+	assert.EqualValues(t, http.StatusTooManyRequests, rateLimitedRequest(router, "", "192.0.2.1:5678").Code)
+	//another ip and an api key sent from the same ip have their own limits
+	assert.EqualValues(t, http.StatusOK, rateLimitedRequest(router, "", "192.0.2.2:1234").Code)
+	assert.EqualValues(t, http.StatusOK, rateLimitedRequest(router, "4f0c2a", "192.0.2.1:1234").Code)
+}
+
+//Checks that a client cannot get a new limit by sending headers it chooses
+func TestRateLimitSpoofedHeaders(t *testing.T) {
+	router := newRateLimitedRouter()
+
+	for i := 0; i < 2; i++ {
+		assert.EqualValues(t, http.StatusOK, rateLimitedRequest(router, "", "192.0.2.1:1234").Code)
+	}
+	//unknown api keys fall back to the ip
+	assert.EqualValues(t, http.StatusTooManyRequests, rateLimitedRequest(router, "random-1", "192.0.2.1:1234").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, rateLimitedRequest(router, "random-2", "192.0.2.1:1234").Code)
+	//the X-Forwarded-For header of a client which is not a trusted proxy is ignored
+	assert.EqualValues(t, http.StatusTooManyRequests, forwardedRequest(router, "", "192.0.2.1:1234", "198.51.100.7").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, forwardedRequest(router, "", "192.0.2.1:1234", "198.51.100.8").Code)
+}
+
+func TestRateLimitTrustedProxies(t *testing.T) {
+	router := newRateLimitedRouter()
+
+	//the client ip is the rightmost hop which is not a trusted proxy, what the client prepended is ignored
+	for i := 0; i < 2; i++ {
+		assert.EqualValues(t, http.StatusOK, forwardedRequest(router, "", "10.0.0.1:1234", "198.51.100.7, 192.0.2.1, 10.0.0.2").Code)
+	}
+	assert.EqualValues(t, http.StatusTooManyRequests, forwardedRequest(router, "", "10.0.0.1:1234", "198.51.100.8, 192.0.2.1").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, rateLimitedRequest(router, "", "192.0.2.1:5678").Code)
+	//another client behind the same proxy has its own limit
+	assert.EqualValues(t, http.StatusOK, forwardedRequest(router, "", "10.0.0.1:1234", "192.0.2.2").Code)
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::1"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"10.0.0.0/8", "192.0.2.1/32", "2001:db8::1/128"},
+		[]string{networks[0].String(), networks[1].String(), networks[2].String()})
+
+	_, err = ParseNetworks([]string{"gateway"})
+	assert.NotNil(t, err)
+}
+
+func TestRateLimitProblem(t *testing.T) {
+	router := newRateLimitedRouter()
+	for i := 0; i < 2; i++ {
+		rateLimitedRequest(router, "", "192.0.2.1:1234")
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/pokemon/charizard", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("Accept", "application/problem+json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	assert.EqualValues(t, http.StatusTooManyRequests, response.Code)
+	assert.EqualValues(t, "application/problem+json", response.Header().Get("Content-Type"))
+	assert.EqualValues(t, "1800", response.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"type":"urn:shakespearing-pokemon:problem:rate-limited","title":"Too Many Requests","status":429,`+
+		`"detail":"too many requests, slow down","instance":"/pokemon/charizard","code":"RATE_LIMITED","retry_after":1800}`, response.Body.String())
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"shakespearing-pokemon/api/logger"
+)
+
+const (
+	requestIDKey = "request_id"
+	//maxRequestIDLength stops clients from flooding the logs through the request id
+	maxRequestIDLength = 128
+)
+
+//RequestID accepts the X-Request-ID header of the client or generates one when missing or invalid, the id is stored
+//in the gin context as well as in the context of the request, so that it reaches the calls to the external apis, and
+//echoed in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(logger.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), requestID))
+		c.Header(logger.RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+//GetRequestID returns the correlation id of the request, empty if the RequestID middleware is not in use
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		logger.Error("error when generating a request id", logger.Fields{"error": err})
+	}
+	return hex.EncodeToString(id)
+}
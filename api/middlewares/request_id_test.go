@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"shakespearing-pokemon/api/logger"
+	"strings"
+	"testing"
+)
+
+func newRequestIDRouter(requestIDs *[]string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/pokemon/:pokemonName", func(c *gin.Context) {
+		*requestIDs = append(*requestIDs, GetRequestID(c))
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRequestIDIsAccepted(t *testing.T) {
+	var requestIDs []string
+	router := newRequestIDRouter(&requestIDs)
+
+	request := httptest.NewRequest(http.MethodGet, "/pokemon/charizard", nil)
+	request.Header.Set(logger.RequestIDHeader, "client-id-123")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	assert.EqualValues(t, []string{"client-id-123"}, requestIDs)
+	assert.EqualValues(t, "client-id-123", response.Header().Get(logger.RequestIDHeader))
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	var requestIDs []string
+	router := newRequestIDRouter(&requestIDs)
+
+	for _, requestID := range []string{"", "with spaces", strings.Repeat("a", 129)} {
+		request := httptest.NewRequest(http.MethodGet, "/pokemon/charizard", nil)
+		request.Header.Set(logger.RequestIDHeader, requestID)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		generated := response.Header().Get(logger.RequestIDHeader)
+		assert.EqualValues(t, 32, len(generated))
+		assert.NotEqual(t, requestID, generated)
+	}
+
+	assert.EqualValues(t, 3, len(requestIDs))
+	assert.NotEqual(t, requestIDs[0], requestIDs[1])
+}
+
+func TestRequestIDReachesRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	var contextRequestID string
+	router.GET("/pokemon/:pokemonName", func(c *gin.Context) {
+		contextRequestID = logger.RequestIDFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/pokemon/charizard", nil)
+	request.Header.Set(logger.RequestIDHeader, "client-id-123")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.EqualValues(t, "client-id-123", contextRequestID)
+}
@@ -0,0 +1,73 @@
+package local_translation_provider
+
+type phrase struct {
+	words       []string
+	replacement string
+}
+
+var (
+	//phrases are replaced before the single words, so that e.g. "it is" becomes "'tis" rather than "'t be"
+	phrases = []phrase{
+		{words: []string{"it", "is"}, replacement: "'tis"},
+		{words: []string{"it", "was"}, replacement: "'twas"},
+		{words: []string{"it", "would"}, replacement: "'twould"},
+		{words: []string{"it", "will"}, replacement: "'twill"},
+		{words: []string{"you", "are"}, replacement: "thou art"},
+		{words: []string{"are", "you"}, replacement: "art thou"},
+		{words: []string{"you", "were"}, replacement: "thou wert"},
+		{words: []string{"you", "have"}, replacement: "thou hast"},
+		{words: []string{"have", "you"}, replacement: "hast thou"},
+		{words: []string{"you", "will"}, replacement: "thou wilt"},
+		{words: []string{"will", "you"}, replacement: "wilt thou"},
+		{words: []string{"you", "do"}, replacement: "thou dost"},
+		{words: []string{"do", "you"}, replacement: "dost thou"},
+		{words: []string{"you", "can"}, replacement: "thou canst"},
+		{words: []string{"can", "you"}, replacement: "canst thou"},
+	}
+
+	//lexicon maps lower case modern English words to their archaic counterparts
+	lexicon = map[string]string{
+		"you":      "thou",
+		"your":     "thy",
+		"yours":    "thine",
+		"yourself": "thyself",
+		"you're":   "thou art",
+		"you've":   "thou hast",
+		"you'll":   "thou wilt",
+		"is":       "be",
+		"isn't":    "be not",
+		"it":       "'t",
+		"it's":     "'tis",
+		"has":      "hath",
+		"does":     "doth",
+		"doesn't":  "doth not",
+		"don't":    "do not",
+		"can't":    "cannot",
+		"won't":    "shall not",
+		"says":     "saith",
+		"that":     "yond",
+		"around":   "'round",
+		"anything": "aught",
+		"nothing":  "nought",
+		"never":    "nev'r",
+		"ever":     "e'er",
+		"over":     "o'er",
+		"even":     "e'en",
+		"often":    "oft",
+		"before":   "ere",
+		"between":  "betwixt",
+		"against":  "'gainst",
+		"among":    "amongst",
+		"until":    "till",
+		"perhaps":  "perchance",
+		"maybe":    "perchance",
+		"soon":     "anon",
+		"very":     "most",
+		"great":    "most wondrous",
+		"enough":   "enow",
+		"yes":      "aye",
+		"hello":    "hail",
+		"listen":   "hark",
+		"why":      "wherefore",
+	}
+)
@@ -0,0 +1,145 @@
+package local_translation_provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/translation/translation_domain"
+	"shakespearing-pokemon/api/providers/translation_provider"
+	"strings"
+	"unicode"
+)
+
+type localTranslationProvider struct{}
+
+type untranslatedProvider struct{}
+
+//NewLocalTranslationProvider creates a translator to Shakespearean English which does not call any external api, it is
+//used when the FunTranslations API cannot translate
+func NewLocalTranslationProvider() translation_provider.Translator {
+	return &localTranslationProvider{}
+}
+
+//NewUntranslatedProvider creates a translator returning the text untouched whatever the style, it is the last resort
+//when nothing else can translate
+func NewUntranslatedProvider() translation_provider.Translator {
+	return &untranslatedProvider{}
+}
+
+//Translate rewrites the text with the archaic English phrases and words of the lexicon, only English texts and the
+//default style are supported. The text is expected as normalized by flavortext.Normalize
+func (l *localTranslationProvider) Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+	if !request.IsDefaultLanguage() {
+		return nil, api_error.New(http.StatusUnprocessableEntity, api_error.CodeUnsupportedLanguage,
+			fmt.Sprintf("the local translator only translates English descriptions, not %q ones", request.Language))
+	}
+	if request.Style != "" && request.Style != translation_domain.DefaultStyle {
+		return nil, api_error.New(http.StatusBadRequest, api_error.CodeUnknownStyle,
+			fmt.Sprintf("the local translator does not translate to style %q", request.Style))
+	}
+
+	return &translation_domain.TranslationResponse{
+		Content: translation_domain.ContentFields{Translation: translate(request.Text)},
+	}, nil
+}
+
+func (u *untranslatedProvider) Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+	return &translation_domain.TranslationResponse{
+		Content: translation_domain.ContentFields{Translation: request.Text},
+	}, nil
+}
+
+type token struct {
+	text string
+	word bool
+}
+
+func translate(text string) string {
+	tokens := tokenize(text)
+	var translation strings.Builder
+	for i := 0; i < len(tokens); {
+		if tokens[i].word {
+			if replacement, n, ok := matchPhrase(tokens[i:]); ok {
+				translation.WriteString(matchCase(tokens[i].text, replacement))
+				i += n
+				continue
+			}
+			if replacement, ok := lexicon[strings.ToLower(tokens[i].text)]; ok {
+				translation.WriteString(matchCase(tokens[i].text, replacement))
+				i++
+				continue
+			}
+		}
+		translation.WriteString(tokens[i].text)
+		i++
+	}
+	return translation.String()
+}
+
+//tokenize splits the text into words and the whitespaces and punctuation between them, an apostrophe between two
+//letters belongs to the word, e.g. "it's" or "Farfetch'd"
+func tokenize(text string) []token {
+	runes := []rune(text)
+	isWordRune := func(i int) bool {
+		if unicode.IsLetter(runes[i]) {
+			return true
+		}
+		return runes[i] == '\'' && i > 0 && i < len(runes)-1 && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1])
+	}
+
+	var tokens []token
+	for start := 0; start < len(runes); {
+		word := isWordRune(start)
+		end := start + 1
+		for end < len(runes) && isWordRune(end) == word {
+			end++
+		}
+		tokens = append(tokens, token{text: string(runes[start:end]), word: word})
+		start = end
+	}
+	return tokens
+}
+
+//matchPhrase reports whether the tokens start with a phrase whose words are separated by single spaces, and how many
+//tokens the phrase spans
+func matchPhrase(tokens []token) (string, int, bool) {
+	for _, p := range phrases {
+		n := 2*len(p.words) - 1
+		if len(tokens) < n {
+			continue
+		}
+		matched := true
+		for i, word := range p.words {
+			if !strings.EqualFold(tokens[2*i].text, word) || (i > 0 && tokens[2*i-1].text != " ") {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return p.replacement, n, true
+		}
+	}
+	return "", 0, false
+}
+
+//matchCase gives the replacement the case of the source word: upper case words stay upper case and capitalized words,
+//e.g. at the start of a sentence, stay capitalized
+func matchCase(source string, replacement string) string {
+	runes := []rune(source)
+	if len(runes) > 1 && strings.ToUpper(source) == source {
+		return strings.ToUpper(replacement)
+	}
+	if !unicode.IsUpper(runes[0]) {
+		return replacement
+	}
+
+	replacementRunes := []rune(replacement)
+	for i, r := range replacementRunes {
+		if unicode.IsLetter(r) {
+			replacementRunes[i] = unicode.ToUpper(r)
+			break
+		}
+	}
+	return string(replacementRunes)
+}
@@ -0,0 +1,59 @@
+package local_translation_provider
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/translation/translation_domain"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	testCases := []struct {
+		text        string
+		translation string
+	}{
+		{
+			text:        "Charizard flies around the sky in search of powerful opponents. It breathes fire of such great heat that it melts anything.",
+			translation: "Charizard flies 'round the sky in search of powerful opponents. 'T breathes fire of such most wondrous heat yond 't melts aught.",
+		},
+		{text: "It is said that you are never alone.", translation: "'Tis said yond thou art nev'r alone."},
+		{text: "Your Pokemon is strong, isn't it?", translation: "Thy Pokemon be strong, be not 't?"},
+		{text: "It's hot! Do you know why?", translation: "'Tis hot! Dost thou know wherefore?"},
+		{text: "IT IS HOT", translation: "'TIS HOT"},
+		//a phrase is not matched across punctuation
+		{text: "Touch it, is it hot?", translation: "Touch 't, be 't hot?"},
+		{text: "Farfetch'd holds a leek.", translation: "Farfetch'd holds a leek."},
+		{text: "", translation: ""},
+	}
+
+	for _, testCase := range testCases {
+		assert.EqualValues(t, testCase.translation, translate(testCase.text), testCase.text)
+	}
+}
+
+func TestTranslateStyle(t *testing.T) {
+	response, errorResponse := NewLocalTranslationProvider().Translate(context.Background(), translation_domain.TranslationRequest{Text: "It is hot", Style: "shakespeare"})
+	assert.Nil(t, errorResponse)
+	assert.EqualValues(t, "'Tis hot", response.Content.Translation)
+
+	response, errorResponse = NewLocalTranslationProvider().Translate(context.Background(), translation_domain.TranslationRequest{Text: "It is hot", Style: "yoda"})
+	assert.Nil(t, response)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusBadRequest, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUnknownStyle, errorResponse.Code())
+	assert.EqualValues(t, `the local translator does not translate to style "yoda"`, errorResponse.Message())
+
+	response, errorResponse = NewLocalTranslationProvider().Translate(context.Background(), translation_domain.TranslationRequest{Text: "Il fait chaud", Language: "fr"})
+	assert.Nil(t, response)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUnsupportedLanguage, errorResponse.Code())
+}
+
+func TestUntranslated(t *testing.T) {
+	response, errorResponse := NewUntranslatedProvider().Translate(context.Background(), translation_domain.TranslationRequest{Text: "It is hot", Style: "yoda"})
+	assert.Nil(t, errorResponse)
+	assert.EqualValues(t, "It is hot", response.Content.Translation)
+}
@@ -1,99 +1,98 @@
 package pokemon_provider
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
+	url2 "net/url"
 	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/clients/upstream"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/pokemon/pokemon_domain"
-	"shakespearing-pokemon/api/domains/pokemon/pokemon_error"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"shakespearing-pokemon/api/utils/singleflight"
 )
 
 const (
-	pokemonInfoUrl = "https://pokeapi.co/api/v2/pokemon-species/%s"
+	providerName = "pokemon_provider"
+	//upstreamName tells the clients which external api failed their request
+	upstreamName = "pokeapi"
+
+	pokemonInfoPath = "/pokemon-species/%s"
 )
 
-type pokemonProvider struct{}
+//PokemonInfoProvider gets the species information of a pokemon, whatever the source
+type PokemonInfoProvider interface {
+	GetPokemonInfo(ctx context.Context, request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error)
+}
+
+//Provider gets the species information from the PokeAPI
+type Provider struct {
+	upstream *upstream.Upstream
 
-type pokemonProviderInterface interface {
-	GetPokemonInfo(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *pokemon_error.PokemonError)
+	//requests collapses concurrent requests for the same pokemon into a single call to the external api
+	requests singleflight.Group
 }
 
-var (
-	//PokemonProvider is used to mock the provider in test
-	PokemonProvider pokemonProviderInterface = &pokemonProvider{}
-)
+//New creates a provider calling the PokeAPI deployment of the config through the client, its calls are recorded to the
+//registry
+func New(client restclient.ClientInterface, cfg config.UpstreamConfig, registry *metrics.Registry) *Provider {
+	return &Provider{upstream: upstream.New(client, upstream.Names{
+		Provider: providerName,
+		Upstream: upstreamName,
+		Title:    "PokeAPI",
+		Results:  "pokemon info",
+	}, cfg, registry)}
+}
 
-func (p *pokemonProvider) GetPokemonInfo(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *pokemon_error.PokemonError) {
-	url := fmt.Sprintf(pokemonInfoUrl, request.Name)
-	bytes, errorResponse := getResults(url)
-	if errorResponse != nil {
-		return nil, errorResponse
-	}
+//CircuitBreakerState returns the state of the circuit breaker guarding the PokeAPI
+func (p *Provider) CircuitBreakerState() circuitbreaker.State {
+	return p.upstream.CircuitBreakerState()
+}
 
-	var result pokemon_domain.PokemonInfoResponse
-	err := json.Unmarshal(bytes, &result)
-	errorResponse = createErrorResponse(err, "error when trying to unmarshal pokemon information response from API")
-	if errorResponse != nil {
-		return nil, errorResponse
-	}
+//BaseUrl returns the url of the PokeAPI deployment the provider calls
+func (p *Provider) BaseUrl() string {
+	return p.upstream.BaseUrl()
+}
 
-	return &result, nil
+type pokemonInfoResult struct {
+	response      *pokemon_domain.PokemonInfoResponse
+	errorResponse *api_error.Error
 }
 
-func getResults(url string) ([]byte, *pokemon_error.PokemonError) {
-	response, err := restclient.ClientStruct.Get(url)
-	errorResponse := createErrorResponse(err, "error when trying to get pokemon info results")
-	if errorResponse != nil {
-		return []byte{}, errorResponse
+func (p *Provider) GetPokemonInfo(ctx context.Context, request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+	url := p.upstream.BaseUrl() + fmt.Sprintf(pokemonInfoPath, url2.PathEscape(request.Name))
+	value, _, err := p.requests.Do(ctx, url, func(ctx context.Context) interface{} {
+		response, errorResponse := p.getPokemonInfo(ctx, url)
+		return pokemonInfoResult{response: response, errorResponse: errorResponse}
+	})
+	if err != nil {
+		return nil, p.upstream.Error(ctx, err, "error when waiting for the pokemon info results")
 	}
 
-	bytes, errorResponse := checkResponseBody(response)
-	if errorResponse != nil {
-		return []byte{}, errorResponse
-	}
-	return bytes, nil
+	result := value.(pokemonInfoResult)
+	return result.response, result.errorResponse
 }
 
-func checkResponseBody(response *http.Response) ([]byte, *pokemon_error.PokemonError) {
-	bytes, err := ioutil.ReadAll(response.Body)
-	defer response.Body.Close()
-
-	errorResponse := createErrorResponse(err, "error when parsing the pokemon info response body")
+func (p *Provider) getPokemonInfo(ctx context.Context, url string) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+	response, bytes, errorResponse := p.upstream.Get(ctx, url, nil)
 	if errorResponse != nil {
-		return []byte{}, errorResponse
+		return nil, errorResponse
+	}
+	//catch non-existent pokemon error
+	if response.StatusCode == 404 {
+		return nil, api_error.New(http.StatusNotFound, api_error.CodePokemonNotFound, "pokemon not found")
 	}
-
 	if response.StatusCode > 299 {
-		//catch non-existent pokemon error
-		if response.StatusCode == 404 {
-			errorResponse := &pokemon_error.PokemonError{}
-			errorResponse.Code = http.StatusNotFound
-			errorResponse.ErrorMessage = "pokemon not found"
-			return nil, errorResponse
-		}
-		errorResponse := &pokemon_error.PokemonError{}
-		errorResponse.Code = http.StatusInternalServerError
-		errorResponse.ErrorMessage = "error from external api"
-		if err := json.Unmarshal(bytes, &errorResponse); err != nil {
-			errorResponse.ErrorMessage = fmt.Sprintf("error when unmarshaling request from external api: %s", err.Error())
-		}
-		return nil, errorResponse
+		return nil, p.upstream.StatusError(response, "error from external api")
 	}
 
-	return bytes, nil
-}
-
-func createErrorResponse(err error, errorMsg string) *pokemon_error.PokemonError {
-	if err != nil {
-		errorMsg := fmt.Sprintf(errorMsg+": %s", err.Error())
-		log.Println(errorMsg)
-		return &pokemon_error.PokemonError{
-			Code:         http.StatusBadRequest,
-			ErrorMessage: errorMsg,
-		}
+	var result pokemon_domain.PokemonInfoResponse
+	if errorResponse := p.upstream.Decode(ctx, bytes, &result, "pokemon information response"); errorResponse != nil {
+		return nil, errorResponse
 	}
-	return nil
+
+	return &result, nil
 }
@@ -2,24 +2,33 @@ package pokemon_provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/pokemon/pokemon_domain"
-	"shakespearing-pokemon/api/domains/pokemon/pokemon_error"
+	"shakespearing-pokemon/api/logger"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
-var getRequestFunc func(url string) (*http.Response, error)
-
-type getClientMock struct{}
+type getClientMock struct {
+	getRequestFunc func(ctx context.Context, url string, headers http.Header) (*http.Response, error)
+}
 
-func (c *getClientMock) Get(request string) (*http.Response, error) {
-	return getRequestFunc(request)
+func (c *getClientMock) Get(ctx context.Context, request string, headers http.Header) (*http.Response, error) {
+	return c.getRequestFunc(ctx, request, headers)
 }
 
 func TestGetPokemonInfo(t *testing.T) {
@@ -54,16 +63,16 @@ func TestGetPokemonInfo(t *testing.T) {
 
 	r := bytes.NewReader(b)
 
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusOK,
 			Body:       ioutil.NopCloser(r),
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
 
-	actualResponse, errorResponse := PokemonProvider.GetPokemonInfo(pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
 	assert.Nil(t, errorResponse)
 	assert.NotNil(t, actualResponse)
 	assert.EqualValues(t, expectedResponse.Name, actualResponse.Name)
@@ -73,35 +82,45 @@ func TestGetPokemonInfo(t *testing.T) {
 	}
 }
 
-func TestGetPokemonInfoInvalidErrorFormatting(t *testing.T) {
-	expectedResponse := pokemon_error.PokemonError{
-		Code:         http.StatusForbidden,
-		ErrorMessage: "error occurred whilst un-marshaling error expectedResponse from api",
+//Checks that the name cannot reach another path of the external api
+func TestGetPokemonInfoEscapesName(t *testing.T) {
+	var urls []string
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+		urls = append(urls, url)
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader("Not Found"))}, nil
 	}
+	cfg := config.Default().PokeAPI
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, cfg, metrics.NewRegistry())
 
-	b, err := json.Marshal(expectedResponse)
-	assert.Nil(t, err)
-	assert.NotNil(t, b)
-
-	r := bytes.NewReader(b)
+	for _, name := range []string{"../pokemon/1", "a?b"} {
+		provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: name})
+	}
+	assert.EqualValues(t, []string{
+		cfg.BaseUrl + "/pokemon-species/..%2Fpokemon%2F1",
+		cfg.BaseUrl + "/pokemon-species/a%3Fb",
+	}, urls)
+}
 
-	getRequestFunc = func(url string) (*http.Response, error) {
+//Checks that the error body of the external api is not passed through, whatever its format
+func TestGetPokemonInfoUnexpectedStatus(t *testing.T) {
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusForbidden,
-			Body:       ioutil.NopCloser(r),
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error": {"code": 403, "message": "forbidden"}}`)),
 		}, nil
 	}
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
 
-	actualResponse, errorResponse := PokemonProvider.GetPokemonInfo(pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
 	assert.Nil(t, actualResponse)
-	assert.EqualValues(t, expectedResponse.Code, errorResponse.Code)
-	assert.EqualValues(t, expectedResponse.ErrorMessage, errorResponse.ErrorMessage)
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUpstreamError, errorResponse.Code())
+	assert.EqualValues(t, "error from external api", errorResponse.Message())
 }
 
 func TestGetPokemonInfoInvalidBodyArguments(t *testing.T) {
 	t.Parallel()
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
 		invalidCloser, _ := os.Open("-asf3")
 		return &http.Response{
 			StatusCode: http.StatusBadRequest,
@@ -109,37 +128,37 @@ func TestGetPokemonInfoInvalidBodyArguments(t *testing.T) {
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
 
-	actualResponse, errorResponse := PokemonProvider.GetPokemonInfo(pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
 	assert.Nil(t, actualResponse)
 	assert.NotNil(t, errorResponse)
-	assert.EqualValues(t, "error when parsing the pokemon info response body: invalid argument", errorResponse.ErrorMessage)
-	assert.EqualValues(t, http.StatusBadRequest, errorResponse.Code)
+	assert.EqualValues(t, "error when parsing the pokemon info response body: invalid argument", errorResponse.Message())
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
 }
 
 //Checks in case the error response is invalid, it can happen when the external api changes their error response's data types
 func TestGetPokemonInfosInvalidErrorInterface(t *testing.T) {
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusBadRequest,
 			Body:       ioutil.NopCloser(strings.NewReader(`{"random'": 2020}`)),
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
 
-	actualResponse, errorResponse := PokemonProvider.GetPokemonInfo(pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
 	assert.Nil(t, actualResponse)
 	assert.NotNil(t, errorResponse)
-	assert.EqualValues(t, "error from external api", errorResponse.ErrorMessage)
-	assert.EqualValues(t, http.StatusInternalServerError, errorResponse.Code)
+	assert.EqualValues(t, "error from external api", errorResponse.Message())
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
 }
 
 //Checks whether even though we are getting a positive response, the data types of the response might not be the same
 //if in that case, the external api changed the data types, the unmarshaling will fail
 func TestGetPokemonInfoInvalidResponseInterface(t *testing.T) {
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusOK,
 			Body: ioutil.NopCloser(strings.NewReader(`{
@@ -164,15 +183,262 @@ func TestGetPokemonInfoInvalidResponseInterface(t *testing.T) {
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
 
-	actualResponse, errorResponse := PokemonProvider.GetPokemonInfo(pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "doesn't matter"})
 	assert.Nil(t, actualResponse)
 	assert.NotNil(t, errorResponse)
 	assert.EqualValues(t,
 		"error when trying to unmarshal pokemon information response from API: invalid character 'T' looking for beginning of value",
-		errorResponse.ErrorMessage)
-	assert.EqualValues(t, http.StatusBadRequest, errorResponse.Code)
+		errorResponse.Message())
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUpstreamBadResponse, errorResponse.Code())
+}
+
+func TestGetPokemonInfoForwardsRequestID(t *testing.T) {
+	var requestHeaders http.Header
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+		requestHeaders = headers
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"name": "charizard"}`)),
+		}, nil
+	}
+
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
+
+	_, errorResponse := provider.GetPokemonInfo(logger.ContextWithRequestID(context.Background(), "4f0c2a"), pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+	assert.Nil(t, errorResponse)
+	assert.EqualValues(t, "4f0c2a", requestHeaders.Get(logger.RequestIDHeader))
+}
+
+func TestGetPokemonInfoResponseTimeout(t *testing.T) {
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	cfg := config.Default().PokeAPI
+	cfg.ResponseTimeout = config.Duration{Duration: 10 * time.Millisecond}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, cfg, metrics.NewRegistry())
+
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, "error when trying to get pokemon info results: context deadline exceeded", errorResponse.Message())
+	assert.EqualValues(t, api_error.CodeUpstreamTimeout, errorResponse.Code())
+	assert.EqualValues(t, http.StatusGatewayTimeout, errorResponse.Status())
+}
+
+func TestGetPokemonInfoCallerCancelled(t *testing.T) {
+	finished := make(chan struct{})
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+		defer close(finished)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	actualResponse, errorResponse := provider.GetPokemonInfo(ctx, pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, "error when waiting for the pokemon info results: context deadline exceeded", errorResponse.Message())
+	assert.EqualValues(t, http.StatusGatewayTimeout, errorResponse.Status())
+	//the call abandoned by its only caller is cancelled as well
+	<-finished
+}
+
+func TestGetPokemonInfoCircuitBreakerOpen(t *testing.T) {
+	var calls int32
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"Code": 503, "ErrorMessage": "service unavailable"}`)),
+		}, nil
+	}
+
+	cfg := config.Default().PokeAPI
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{FailureThreshold: 2, CoolDown: config.Duration{Duration: time.Minute}}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, cfg, metrics.NewRegistry())
+
+	for i := 0; i < 2; i++ {
+		_, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+		assert.NotNil(t, errorResponse)
+	}
+	assert.EqualValues(t, circuitbreaker.Open, provider.CircuitBreakerState())
+
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusServiceUnavailable, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUpstreamUnavailable, errorResponse.Code())
+	assert.EqualValues(t, "the PokeAPI is unavailable, circuit breaker is open", errorResponse.Message())
+	assert.EqualValues(t, 60, errorResponse.RetryAfter())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+//Checks that concurrent requests for the same pokemon share a single call to the external api
+func TestGetPokemonInfoConcurrentRequests(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	getRequestFunc := func(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(strings.NewReader("Not Found")),
+		}, nil
+	}
+
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, config.Default().PokeAPI, metrics.NewRegistry())
+
+	const requests = 5
+	var wg sync.WaitGroup
+	errorResponses := make(chan *api_error.Error, requests)
+	getPokemonInfo := func() {
+		defer wg.Done()
+		_, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "missingno"})
+		errorResponses <- errorResponse
+	}
+
+	wg.Add(requests)
+	go getPokemonInfo()
+	<-started
+	for i := 1; i < requests; i++ {
+		go getPokemonInfo()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errorResponses)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	for errorResponse := range errorResponses {
+		assert.NotNil(t, errorResponse)
+		assert.EqualValues(t, http.StatusNotFound, errorResponse.Status())
+		assert.EqualValues(t, api_error.CodePokemonNotFound, errorResponse.Code())
+		assert.EqualValues(t, "pokemon not found", errorResponse.Message())
+	}
+}
+
+//Checks how the failures of a fake PokeAPI are reported to the client
+func TestGetPokemonInfoUpstreamFailures(t *testing.T) {
+	testCases := []struct {
+		name               string
+		handler            http.HandlerFunc
+		expectedStatus     int
+		expectedCode       api_error.Code
+		expectedRetryAfter int
+	}{
+		{
+			name:           "pokemon not found",
+			handler:        func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   api_error.CodePokemonNotFound,
+		},
+		{
+			name:           "rejected call",
+			handler:        func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadRequest) },
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   api_error.CodeUpstreamError,
+		},
+		{
+			name:           "server error",
+			handler:        func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   api_error.CodeUpstreamError,
+		},
+		{
+			name: "outage",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedCode:       api_error.CodeUpstreamUnavailable,
+			expectedRetryAfter: 120,
+		},
+		{
+			name:           "gateway timeout",
+			handler:        func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusGatewayTimeout) },
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedCode:   api_error.CodeUpstreamTimeout,
+		},
+		{
+			name: "too many requests",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       api_error.CodeUpstreamRateLimited,
+			expectedRetryAfter: 30,
+		},
+		{
+			name:           "undecodable body",
+			handler:        func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("<html>PokeAPI</html>")) },
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   api_error.CodeUpstreamBadResponse,
+		},
+		{
+			name:           "slow response",
+			handler:        func(w http.ResponseWriter, r *http.Request) { <-r.Context().Done() },
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedCode:   api_error.CodeUpstreamTimeout,
+		},
+	}
+
+	for _, testCase := range testCases {
+		server := httptest.NewServer(testCase.handler)
+		cfg := config.Default().PokeAPI
+		cfg.BaseUrl = server.URL
+		cfg.ResponseTimeout = config.Duration{Duration: 50 * time.Millisecond}
+		cfg.Retry = config.RetryConfig{}
+		provider := New(restclient.New(), cfg, metrics.NewRegistry())
+
+		actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+		server.Close()
+		assert.Nil(t, actualResponse, testCase.name)
+		if assert.NotNil(t, errorResponse, testCase.name) {
+			assert.EqualValues(t, testCase.expectedStatus, errorResponse.Status(), testCase.name)
+			assert.EqualValues(t, testCase.expectedCode, errorResponse.Code(), testCase.name)
+			//a pokemon the PokeAPI does not know is no failure of the api
+			if testCase.expectedCode != api_error.CodePokemonNotFound {
+				assert.EqualValues(t, "pokeapi", errorResponse.Upstream(), testCase.name)
+			}
+			assert.EqualValues(t, testCase.expectedRetryAfter, errorResponse.RetryAfter(), testCase.name)
+		}
+	}
+}
+
+//Checks that a PokeAPI which cannot be reached is reported as a bad gateway and a call abandoned by the client as such
+func TestGetPokemonInfoUpstreamUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cfg := config.Default().PokeAPI
+	cfg.BaseUrl = server.URL
+	cfg.Retry = config.RetryConfig{}
+	server.Close()
+	provider := New(restclient.New(), cfg, metrics.NewRegistry())
+
+	_, errorResponse := provider.GetPokemonInfo(context.Background(), pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUpstreamError, errorResponse.Code())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errorResponse = provider.GetPokemonInfo(ctx, pokemon_domain.PokemonInfoRequest{Name: "charizard"})
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, api_error.StatusClientClosedRequest, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeRequestCanceled, errorResponse.Code())
 }
 
 func TestGetPokemonInfoIntegration(t *testing.T) {
@@ -185,7 +451,8 @@ func TestGetPokemonInfoIntegration(t *testing.T) {
 		Name: "charizard",
 	}
 
-	actualResponse, errorResponse := PokemonProvider.GetPokemonInfo(request)
+	provider := New(restclient.New(), config.Default().PokeAPI, metrics.NewRegistry())
+	actualResponse, errorResponse := provider.GetPokemonInfo(context.Background(), request)
 	assert.Nil(t, errorResponse)
 	if len(actualResponse.Description) == 0 {
 		assert.Fail(t, "pokemon info from API is empty")
@@ -0,0 +1,44 @@
+package translation_provider
+
+import (
+	"shakespearing-pokemon/api/domains/translation/translation_domain"
+	"sort"
+)
+
+var (
+	//styles maps the translation styles offered by the service to the FunTranslations endpoint translating to them
+	styles = map[string]string{
+		"shakespeare":  "shakespeare",
+		"yoda":         "yoda",
+		"pirate":       "pirate",
+		"minion":       "minion",
+		"valley-speak": "valspeak",
+		"sith":         "sith",
+		"gungan":       "gungan",
+		"cockney":      "cockney",
+		"old-english":  "oldenglish",
+	}
+)
+
+//Styles returns the supported translation styles sorted by name
+func Styles() []string {
+	names := make([]string, 0, len(styles))
+	for name := range styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//IsSupportedStyle reports whether texts can be translated to style, the empty style stands for the default one
+func IsSupportedStyle(style string) bool {
+	_, ok := styles[resolveStyle(style)]
+	return ok
+}
+
+func resolveStyle(style string) string {
+	if style == "" {
+		return translation_domain.DefaultStyle
+	}
+	return style
+}
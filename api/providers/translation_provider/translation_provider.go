@@ -1,99 +1,190 @@
 package translation_provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	url2 "net/url"
 	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/clients/upstream"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/translation/translation_domain"
-	"shakespearing-pokemon/api/domains/translation/translation_error"
-	"strings"
+	"shakespearing-pokemon/api/logger"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"shakespearing-pokemon/api/utils/flavortext"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"shakespearing-pokemon/api/utils/singleflight"
+	"strconv"
 )
 
 const (
-	shakespeareTranslationUrl = "https://api.funtranslations.com/translate/shakespeare.json?text=\"%s\""
+	providerName = "translation_provider"
+	//upstreamName tells the clients which external api failed their request
+	upstreamName = "funtranslations"
+
+	translationPath      = "/%s.json?text=\"%s\""
+	quotaRemainingHeader = "X-RateLimit-Remaining"
 )
 
-type translationProvider struct{}
+//Translator translates a text to the style asked by the request, whatever the engine doing the translation
+type Translator interface {
+	Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error)
+}
 
-type translationProviderInterface interface {
-	GetShakespeareanTranslation(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *translation_error.TranslationError)
+//Provider translates with the FunTranslations API, every provider has its own quota so that providers configured
+//differently do not affect each other
+type Provider struct {
+	upstream *upstream.Upstream
+	config   config.TranslationConfig
+
+	//requests collapses concurrent requests for the same text and style into a single call to the external api, so
+	//that the translation quota is spent only once
+	requests singleflight.Group
+	//quota spends the translation quota evenly instead of letting the external api reject the calls
+	quota   *ratelimit.Limiter
+	metrics *metrics.Registry
 }
 
-var (
-	//TranslationProvider is used to mock teh provider in test
-	TranslationProvider translationProviderInterface = &translationProvider{}
-)
+//New creates a provider calling the FunTranslations deployment of the config through the client, with the whole quota
+//available, its calls are recorded to the registry
+func New(client restclient.ClientInterface, cfg config.TranslationConfig, registry *metrics.Registry) *Provider {
+	return &Provider{
+		upstream: upstream.New(client, upstream.Names{
+			Provider: providerName,
+			Upstream: upstreamName,
+			Title:    "FunTranslations API",
+			Results:  "translation",
+		}, cfg.UpstreamConfig, registry),
+		config:  cfg,
+		quota:   ratelimit.New(cfg.Quota.LimiterConfig()),
+		metrics: registry,
+	}
+}
+
+//CircuitBreakerState returns the state of the circuit breaker guarding the FunTranslations API
+func (p *Provider) CircuitBreakerState() circuitbreaker.State {
+	return p.upstream.CircuitBreakerState()
+}
+
+//QuotaStatus returns the translation calls which can still be made and the time the whole quota is available again,
+//so that a translation can be avoided when the quota is about to be spent
+func (p *Provider) QuotaStatus() ratelimit.Status {
+	return p.quota.Status()
+}
+
+//BaseUrl returns the url of the FunTranslations deployment the provider calls
+func (p *Provider) BaseUrl() string {
+	return p.upstream.BaseUrl()
+}
+
+type translationResult struct {
+	response      *translation_domain.TranslationResponse
+	errorResponse *api_error.Error
+}
+
+//Translate translates the text with the FunTranslations endpoint of the requested style, the text has to be written in
+//English
+func (p *Provider) Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+	if !request.IsDefaultLanguage() {
+		return nil, api_error.New(http.StatusUnprocessableEntity, api_error.CodeUnsupportedLanguage,
+			fmt.Sprintf("the FunTranslations API only translates English descriptions, not %q ones", request.Language))
+	}
+
+	endpoint, ok := styles[resolveStyle(request.Style)]
+	if !ok {
+		return nil, api_error.New(http.StatusBadRequest, api_error.CodeUnknownStyle, fmt.Sprintf("unknown translation style %q", request.Style))
+	}
+
+	url := p.upstream.BaseUrl() + fmt.Sprintf(translationPath, endpoint, url2.QueryEscape(request.Text))
+	value, _, err := p.requests.Do(ctx, url, func(ctx context.Context) interface{} {
+		response, errorResponse := p.getTranslation(ctx, url)
+		return translationResult{response: response, errorResponse: errorResponse}
+	})
+	if err != nil {
+		return nil, p.upstream.Error(ctx, err, "error when waiting for the translation results")
+	}
 
-func (t *translationProvider) GetShakespeareanTranslation(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *translation_error.TranslationError) {
-	url := fmt.Sprintf(shakespeareTranslationUrl, url2.QueryEscape(strings.Replace(request.Text, "\n", "", -1)))
-	bytes, errorResponse := getResults(url)
+	result := value.(translationResult)
+	return result.response, result.errorResponse
+}
+
+func (p *Provider) getTranslation(ctx context.Context, url string) (*translation_domain.TranslationResponse, *api_error.Error) {
+	response, bytes, errorResponse := p.upstream.Get(ctx, url, p.waitQuota)
 	if errorResponse != nil {
 		return nil, errorResponse
 	}
 
+	p.recordQuota(response)
+	if response.StatusCode == http.StatusTooManyRequests {
+		p.quota.Observe(0)
+	}
+	if response.StatusCode > 299 {
+		return nil, p.upstreamError(response, bytes)
+	}
+
 	var result translation_domain.TranslationResponse
-	err := json.Unmarshal(bytes, &result)
-	errorResponse = createErrorResponse(err, "error when trying to unmarshal translation response from API")
-	if errorResponse != nil {
+	if errorResponse := p.upstream.Decode(ctx, bytes, &result, "translation response"); errorResponse != nil {
 		return nil, errorResponse
 	}
 
-	//remove trailing escaped inverted commas
-	result.Content.Translation = strings.Replace(result.Content.Translation, "\"", "", -1)
+	//the text is sent quoted, the translation is quoted too
+	result.Content.Translation = flavortext.Unquote(result.Content.Translation)
 
 	return &result, nil
 }
 
-func getResults(url string) ([]byte, *translation_error.TranslationError) {
-	response, err := restclient.ClientStruct.Get(url)
-	errorResponse := createErrorResponse(err, "error when trying to get translation results")
-	if errorResponse != nil {
-		return []byte{}, errorResponse
+//waitQuota waits up to MaxWait for the translation quota to allow a call
+func (p *Provider) waitQuota(ctx context.Context) *api_error.Error {
+	wait, err := p.quota.Wait(ctx, p.config.Quota.MaxWait.Duration)
+	if err == nil {
+		return nil
 	}
-
-	bytes, errorResponse := checkResponseBody(response)
-	if errorResponse != nil {
-		return []byte{}, errorResponse
+	if err != ratelimit.ErrLimitExceeded {
+		return p.upstream.Error(ctx, err, "error when waiting for the translation quota")
 	}
-	return bytes, nil
+	logger.FromContext(ctx).Warn("translation quota spent", logger.Fields{"provider": providerName, "retry_after": wait.String()})
+	return api_error.New(http.StatusTooManyRequests, api_error.CodeTranslationQuotaExceeded,
+		"the FunTranslations API quota is spent").WithRetryAfter(ratelimit.RetryAfterSeconds(wait)).WithUpstream(upstreamName)
 }
 
-func checkResponseBody(response *http.Response) ([]byte, *translation_error.TranslationError) {
-	bytes, err := ioutil.ReadAll(response.Body)
-	defer response.Body.Close()
+//upstreamErrorBody is the error body of the FunTranslations API
+type upstreamErrorBody struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
 
-	errorResponse := createErrorResponse(err, "error when parsing the translation response body")
-	if errorResponse != nil {
-		return []byte{}, errorResponse
+//upstreamError reports the error the FunTranslations API responded with, its message is kept when the body can be
+//decoded. A 429 means the quota shared with the other clients of the api is spent, the client is told to retry when
+//the api says so, or else when the local quota allows another call
+func (p *Provider) upstreamError(response *http.Response, body []byte) *api_error.Error {
+	message := "error from external api"
+	var errorBody upstreamErrorBody
+	if err := json.Unmarshal(body, &errorBody); err == nil && errorBody.Error.Message != "" {
+		message = errorBody.Error.Message
 	}
 
-	if response.StatusCode > 299 {
-		errorResponse := &translation_error.TranslationError{}
-		errorResponse.Error.Code = http.StatusInternalServerError
-		errorResponse.Error.Message = "error from external api"
-		if err := json.Unmarshal(bytes, &errorResponse); err != nil {
-			errorResponse.Error.Message = fmt.Sprintf("error when unmarshaling request from external api: %s", err.Error())
-		}
-		return nil, errorResponse
+	if response.StatusCode != http.StatusTooManyRequests {
+		return p.upstream.StatusError(response, message)
 	}
-
-	return bytes, nil
+	retryAfter, ok := restclient.RetryAfter(response)
+	if !ok {
+		retryAfter = p.quota.UntilNext()
+	}
+	return api_error.New(http.StatusTooManyRequests, api_error.CodeTranslationQuotaExceeded, message).
+		WithRetryAfter(ratelimit.RetryAfterSeconds(retryAfter)).WithUpstream(upstreamName)
 }
 
-func createErrorResponse(err error, errorMsg string) *translation_error.TranslationError {
+//recordQuota reads the remaining translation quota the external api reports in its response headers
+func (p *Provider) recordQuota(response *http.Response) {
+	remaining, err := strconv.Atoi(response.Header.Get(quotaRemainingHeader))
 	if err != nil {
-		errorMsg := fmt.Sprintf(errorMsg+": %s", err.Error())
-		log.Println(errorMsg)
-		return &translation_error.TranslationError{
-			Error: translation_error.ErrorFields{
-				Code:    http.StatusBadRequest,
-				Message: errorMsg,
-			},
-		}
+		return
 	}
-	return nil
+	p.metrics.SetTranslationQuotaRemaining(remaining)
+	p.quota.Observe(remaining)
 }
@@ -2,24 +2,40 @@ package translation_provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/translation/translation_domain"
-	"shakespearing-pokemon/api/domains/translation/translation_error"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
-var getRequestFunc func(url string) (*http.Response, error)
+type getClientMock struct {
+	getRequestFunc func(url string) (*http.Response, error)
+}
 
-type getClientMock struct{}
+func (c *getClientMock) Get(ctx context.Context, request string, headers http.Header) (*http.Response, error) {
+	return c.getRequestFunc(request)
+}
 
-func (c *getClientMock) Get(request string) (*http.Response, error) {
-	return getRequestFunc(request)
+//testConfig keeps the tests from running out of translation quota
+func testConfig() config.TranslationConfig {
+	cfg := config.Default().Translation
+	cfg.Quota.Limit = 1000
+	cfg.Quota.Window = config.Duration{Duration: time.Hour}
+	return cfg
 }
 
 func TestGetShakespeareanTranslation(t *testing.T) {
@@ -37,52 +53,40 @@ func TestGetShakespeareanTranslation(t *testing.T) {
 
 	r := bytes.NewReader(b)
 
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(url string) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusOK,
 			Body:       ioutil.NopCloser(r),
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), metrics.NewRegistry())
 
-	actualResponse, errorResponse := TranslationProvider.GetShakespeareanTranslation(translation_domain.TranslationRequest{Text: "deosn't matter"})
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "deosn't matter"})
 	assert.Nil(t, errorResponse)
 	assert.NotNil(t, actualResponse)
 	assert.EqualValues(t, expectedResponse.Content.Translation, actualResponse.Content.Translation)
 }
 
 func TestGetShakespeareanTranslationInvalidErrorFormatting(t *testing.T) {
-	expectedResponse := translation_error.TranslationError{
-		Error: translation_error.ErrorFields{
-			Code:    http.StatusForbidden,
-			Message: "error occurred whilst un-marshaling error expectedResponse from api",
-		},
-	}
-
-	b, err := json.Marshal(expectedResponse)
-	assert.Nil(t, err)
-	assert.NotNil(t, b)
-
-	r := bytes.NewReader(b)
-
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(url string) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusForbidden,
-			Body:       ioutil.NopCloser(r),
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error": {"code": 403, "message": "error occurred whilst un-marshaling error expectedResponse from api"}}`)),
 		}, nil
 	}
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), metrics.NewRegistry())
 
-	actualResponse, errorResponse := TranslationProvider.GetShakespeareanTranslation(translation_domain.TranslationRequest{Text: "deosn't matter"})
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "deosn't matter"})
 	assert.Nil(t, actualResponse)
-	assert.EqualValues(t, expectedResponse.Error.Code, errorResponse.Error.Code)
-	assert.EqualValues(t, expectedResponse.Error.Message, errorResponse.Error.Message)
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUpstreamError, errorResponse.Code())
+	assert.EqualValues(t, "error occurred whilst un-marshaling error expectedResponse from api", errorResponse.Message())
 }
 
 func TestGetShakespeareanTranslationInvalidBodyArguments(t *testing.T) {
 	t.Parallel()
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(url string) (*http.Response, error) {
 		invalidCloser, _ := os.Open("-asf3")
 		return &http.Response{
 			StatusCode: http.StatusBadRequest,
@@ -90,37 +94,37 @@ func TestGetShakespeareanTranslationInvalidBodyArguments(t *testing.T) {
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), metrics.NewRegistry())
 
-	actualResponse, errorResponse := TranslationProvider.GetShakespeareanTranslation(translation_domain.TranslationRequest{Text: "deosn't matter"})
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "deosn't matter"})
 	assert.Nil(t, actualResponse)
 	assert.NotNil(t, errorResponse)
-	assert.EqualValues(t, "error when parsing the translation response body: invalid argument", errorResponse.Error.Message)
-	assert.EqualValues(t, http.StatusBadRequest, errorResponse.Error.Code)
+	assert.EqualValues(t, "error when parsing the translation response body: invalid argument", errorResponse.Message())
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
 }
 
 //Checks in case the error response is invalid, it can happen when the external api changes their error response's data types
 func TestGetShakespeareanTranslationInvalidErrorInterface(t *testing.T) {
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(url string) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusBadRequest,
 			Body:       ioutil.NopCloser(strings.NewReader(`{"random'": 2020}`)),
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), metrics.NewRegistry())
 
-	actualResponse, errorResponse := TranslationProvider.GetShakespeareanTranslation(translation_domain.TranslationRequest{Text: "deosn't matter"})
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "deosn't matter"})
 	assert.Nil(t, actualResponse)
 	assert.NotNil(t, errorResponse)
-	assert.EqualValues(t, "error from external api", errorResponse.Error.Message)
-	assert.EqualValues(t, http.StatusInternalServerError, errorResponse.Error.Code)
+	assert.EqualValues(t, "error from external api", errorResponse.Message())
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
 }
 
 //Checks whether even though we are getting a positive response, the data types of the response might not be the same
 //if in that case, the external api changed the data types, the unmarshaling will fail
 func TestGetShakespeareanTranslationInvalidResponseInterface(t *testing.T) {
-	getRequestFunc = func(url string) (*http.Response, error) {
+	getRequestFunc := func(url string) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusOK,
 			Body: ioutil.NopCloser(strings.NewReader(`{
@@ -136,15 +140,330 @@ func TestGetShakespeareanTranslationInvalidResponseInterface(t *testing.T) {
 		}, nil
 	}
 
-	restclient.ClientStruct = &getClientMock{}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), metrics.NewRegistry())
 
-	actualResponse, errorResponse := TranslationProvider.GetShakespeareanTranslation(translation_domain.TranslationRequest{Text: "deosn't matter"})
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "deosn't matter"})
 	assert.Nil(t, actualResponse)
 	assert.NotNil(t, errorResponse)
 	assert.EqualValues(t,
 		"error when trying to unmarshal translation response from API: invalid character ']' looking for beginning of value",
-		errorResponse.Error.Message)
-	assert.EqualValues(t, http.StatusBadRequest, errorResponse.Error.Code)
+		errorResponse.Message())
+	assert.EqualValues(t, http.StatusBadGateway, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUpstreamBadResponse, errorResponse.Code())
+}
+
+func TestGetShakespeareanTranslationCircuitBreakerOpen(t *testing.T) {
+	var calls int32
+	getRequestFunc := func(url string) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error": {"code": 429, "message": "Too Many Requests: Rate limit of 5 requests per hour exceeded."}}`)),
+		}, nil
+	}
+
+	cfg := testConfig()
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{FailureThreshold: 1, CoolDown: config.Duration{Duration: time.Hour}}
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, cfg, metrics.NewRegistry())
+
+	_, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "quota"})
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusTooManyRequests, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeTranslationQuotaExceeded, errorResponse.Code())
+	assert.EqualValues(t, circuitbreaker.Open, provider.CircuitBreakerState())
+
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "quota"})
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusServiceUnavailable, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUpstreamUnavailable, errorResponse.Code())
+	assert.EqualValues(t, "the FunTranslations API is unavailable, circuit breaker is open", errorResponse.Message())
+	assert.EqualValues(t, 3600, errorResponse.RetryAfter())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+}
+
+func TestGetShakespeareanTranslationQuotaSpent(t *testing.T) {
+	var calls int32
+	getRequestFunc := func(url string) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"contents": {"translated": "Lorem ipsum dolor sit amet"}}`)),
+		}, nil
+	}
+
+	cfg := testConfig()
+	cfg.Quota.Limit = 1
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, cfg, metrics.NewRegistry())
+
+	_, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "first"})
+	assert.Nil(t, errorResponse)
+	assert.EqualValues(t, 0, provider.QuotaStatus().Remaining)
+
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "second"})
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusTooManyRequests, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeTranslationQuotaExceeded, errorResponse.Code())
+	assert.EqualValues(t, "the FunTranslations API quota is spent", errorResponse.Message())
+	assert.EqualValues(t, 3600, errorResponse.RetryAfter())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	//a call rejected locally does not count as a failure of the external api
+	assert.EqualValues(t, circuitbreaker.Closed, provider.CircuitBreakerState())
+}
+
+func TestGetShakespeareanTranslationRecordsQuota(t *testing.T) {
+	getRequestFunc := func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Ratelimit-Remaining": []string{"3"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"contents": {"translated": "Lorem ipsum dolor sit amet"}}`)),
+		}, nil
+	}
+
+	registry := metrics.NewRegistry()
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), registry)
+
+	_, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "quota"})
+	assert.Nil(t, errorResponse)
+	//the quota reported by the external api lowers the local one
+	assert.EqualValues(t, 3, provider.QuotaStatus().Remaining)
+
+	response := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, response.Body.String(), "translation_quota_remaining 3")
+	assert.Contains(t, response.Body.String(), `upstream_requests_total{provider="translation_provider",status="200"}`)
+}
+
+func TestTranslateStyle(t *testing.T) {
+	var urls []string
+	getRequestFunc := func(url string) (*http.Response, error) {
+		urls = append(urls, url)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"contents": {"translated": "Lorem ipsum dolor sit amet"}}`)),
+		}, nil
+	}
+
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), metrics.NewRegistry())
+
+	for _, style := range []string{"valley-speak", ""} {
+		_, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Lorem ipsum", Style: style})
+		assert.Nil(t, errorResponse)
+	}
+	assert.EqualValues(t, []string{
+		config.Default().Translation.BaseUrl + `/valspeak.json?text="Lorem+ipsum"`,
+		config.Default().Translation.BaseUrl + `/shakespeare.json?text="Lorem+ipsum"`,
+	}, urls)
+
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Lorem ipsum", Style: "klingon"})
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusBadRequest, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUnknownStyle, errorResponse.Code())
+	assert.EqualValues(t, `unknown translation style "klingon"`, errorResponse.Message())
+
+	actualResponse, errorResponse = provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Lorem ipsum", Language: "fr"})
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errorResponse.Status())
+	assert.EqualValues(t, api_error.CodeUnsupportedLanguage, errorResponse.Code())
+	assert.EqualValues(t, `the FunTranslations API only translates English descriptions, not "fr" ones`, errorResponse.Message())
+	assert.EqualValues(t, 2, len(urls))
+}
+
+func TestStyles(t *testing.T) {
+	assert.Contains(t, Styles(), "yoda")
+	assert.True(t, IsSupportedStyle(""))
+	assert.True(t, IsSupportedStyle("pirate"))
+	assert.False(t, IsSupportedStyle("klingon"))
+}
+
+//Checks that concurrent requests for the same text share a single call to the external api
+func TestGetShakespeareanTranslationConcurrentRequests(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	getRequestFunc := func(url string) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"contents": {"translated": "Lorem ipsum dolor sit amet"}}`)),
+		}, nil
+	}
+
+	provider := New(&getClientMock{getRequestFunc: getRequestFunc}, testConfig(), metrics.NewRegistry())
+
+	const requests = 5
+	var wg sync.WaitGroup
+	responses := make(chan *translation_domain.TranslationResponse, requests)
+	getTranslation := func() {
+		defer wg.Done()
+		response, _ := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Lorem ipsum"})
+		responses <- response
+	}
+
+	wg.Add(requests)
+	go getTranslation()
+	<-started
+	for i := 1; i < requests; i++ {
+		go getTranslation()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(responses)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	for response := range responses {
+		assert.NotNil(t, response)
+		assert.EqualValues(t, "Lorem ipsum dolor sit amet", response.Content.Translation)
+	}
+}
+
+//Checks how the failures of a fake FunTranslations API are reported to the client
+func TestGetShakespeareanTranslationUpstreamFailures(t *testing.T) {
+	testCases := []struct {
+		name               string
+		handler            http.HandlerFunc
+		expectedStatus     int
+		expectedCode       api_error.Code
+		expectedMessage    string
+		expectedRetryAfter int
+	}{
+		{
+			name: "quota spent",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "1800")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error": {"code": 429, "message": "Too Many Requests: Rate limit of 5 requests per hour exceeded."}}`))
+			},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       api_error.CodeTranslationQuotaExceeded,
+			expectedMessage:    "Too Many Requests: Rate limit of 5 requests per hour exceeded.",
+			expectedRetryAfter: 1800,
+		},
+		{
+			//the client is told to retry when the local quota, 1000 calls an hour, allows another call
+			name:               "quota spent without retry after",
+			handler:            func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTooManyRequests) },
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       api_error.CodeTranslationQuotaExceeded,
+			expectedMessage:    "error from external api",
+			expectedRetryAfter: 4,
+		},
+		{
+			name: "rejected call",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": {"code": 400, "message": "Bad Request: text is missing."}}`))
+			},
+			expectedStatus:  http.StatusBadGateway,
+			expectedCode:    api_error.CodeUpstreamError,
+			expectedMessage: "Bad Request: text is missing.",
+		},
+		{
+			name:            "server error",
+			handler:         func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			expectedStatus:  http.StatusBadGateway,
+			expectedCode:    api_error.CodeUpstreamError,
+			expectedMessage: "error from external api",
+		},
+		{
+			name: "outage",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedCode:       api_error.CodeUpstreamUnavailable,
+			expectedMessage:    "error from external api",
+			expectedRetryAfter: 120,
+		},
+		{
+			name:            "undecodable body",
+			handler:         func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{"contents": []}`)) },
+			expectedStatus:  http.StatusBadGateway,
+			expectedCode:    api_error.CodeUpstreamBadResponse,
+			expectedMessage: "error when trying to unmarshal translation response from API: json: cannot unmarshal array into Go struct field TranslationResponse.contents of type translation_domain.ContentFields",
+		},
+		{
+			name:           "slow response",
+			handler:        func(w http.ResponseWriter, r *http.Request) { <-r.Context().Done() },
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedCode:   api_error.CodeUpstreamTimeout,
+		},
+	}
+
+	for _, testCase := range testCases {
+		server := httptest.NewServer(testCase.handler)
+		cfg := testConfig()
+		cfg.BaseUrl = server.URL
+		cfg.ResponseTimeout = config.Duration{Duration: 50 * time.Millisecond}
+		cfg.Retry = config.RetryConfig{}
+		provider := New(restclient.New(), cfg, metrics.NewRegistry())
+
+		actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Spits fire."})
+		server.Close()
+		assert.Nil(t, actualResponse, testCase.name)
+		if assert.NotNil(t, errorResponse, testCase.name) {
+			assert.EqualValues(t, testCase.expectedStatus, errorResponse.Status(), testCase.name)
+			assert.EqualValues(t, testCase.expectedCode, errorResponse.Code(), testCase.name)
+			assert.EqualValues(t, "funtranslations", errorResponse.Upstream(), testCase.name)
+			if testCase.expectedMessage != "" {
+				assert.EqualValues(t, testCase.expectedMessage, errorResponse.Message(), testCase.name)
+			}
+			assert.EqualValues(t, testCase.expectedRetryAfter, errorResponse.RetryAfter(), testCase.name)
+		}
+	}
+}
+
+//the time spent waiting for the quota is not taken from the response timeout of the call
+func TestGetShakespeareanTranslationQueuedKeepsResponseTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"contents": {"translated": "Lorem ipsum dolor sit amet"}}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.BaseUrl = server.URL
+	cfg.ResponseTimeout = config.Duration{Duration: 250 * time.Millisecond}
+	cfg.Quota = config.QuotaConfig{Limit: 1, Window: config.Duration{Duration: 300 * time.Millisecond}, MaxWait: config.Duration{Duration: time.Second}}
+	provider := New(restclient.New(), cfg, metrics.NewRegistry())
+
+	_, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Spits fire."})
+	assert.Nil(t, errorResponse)
+	//the second call queues for the quota longer than its response timeout, then gets the whole timeout
+	start := time.Now()
+	actualResponse, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Breathes fire."})
+	assert.Nil(t, errorResponse)
+	assert.True(t, time.Since(start) > cfg.ResponseTimeout.Duration)
+	if assert.NotNil(t, actualResponse) {
+		assert.EqualValues(t, "Lorem ipsum dolor sit amet", actualResponse.Content.Translation)
+	}
+}
+
+//a call takes a single token of the quota, hence it is not retried by default
+func TestGetShakespeareanTranslationNotRetried(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.BaseUrl = server.URL
+	provider := New(restclient.New(), cfg, metrics.NewRegistry())
+	_, errorResponse := provider.Translate(context.Background(), translation_domain.TranslationRequest{Text: "Spits fire."})
+	assert.NotNil(t, errorResponse)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	assert.EqualValues(t, cfg.Quota.Limit-1, provider.QuotaStatus().Remaining)
 }
 
 func TestGetShakespeareanTranslationIntegration(t *testing.T) {
@@ -154,11 +473,12 @@ func TestGetShakespeareanTranslationIntegration(t *testing.T) {
 	}
 
 	request := translation_domain.TranslationRequest{
-		Text: "CHARIZARD flies around the sky in\nsearch of powerful opponents.\nIt breathes fire of such great heat\f" +
-			"that it melts anything. However, it\nnever turns its fiery breath on any\nopponent weaker than itself.",
+		Text: "Charizard flies around the sky in search of powerful opponents. It breathes fire of such great heat " +
+			"that it melts anything. However, it never turns its fiery breath on any opponent weaker than itself.",
 	}
 
-	actualResponse, errorResponse := TranslationProvider.GetShakespeareanTranslation(request)
+	provider := New(restclient.New(), config.Default().Translation, metrics.NewRegistry())
+	actualResponse, errorResponse := provider.Translate(context.Background(), request)
 	assert.Nil(t, errorResponse)
 	if actualResponse.Content.Translation == "" {
 		assert.Fail(t, "translation from API is empty")
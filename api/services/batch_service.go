@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"sync"
+)
+
+type batchService struct {
+	translationService TranslationServiceInterface
+	maxItems           int
+	workers            int
+	//slots bounds the pokemon translated concurrently by all the batches, so that concurrent batches do not flood the
+	//external apis
+	slots chan struct{}
+}
+
+type BatchServiceInterface interface {
+	GetShakespeareanPokemonTranslations(ctx context.Context, request shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse, *api_error.Error)
+}
+
+//NewBatchService creates a service translating batches of up to MaxItems pokemon with the translation service, up to
+//Workers pokemon of all the batches concurrently
+func NewBatchService(translationService TranslationServiceInterface, cfg config.BatchConfig) BatchServiceInterface {
+	return &batchService{
+		translationService: translationService,
+		maxItems:           cfg.MaxItems,
+		workers:            cfg.Workers,
+		slots:              make(chan struct{}, cfg.Workers),
+	}
+}
+
+//GetShakespeareanPokemonTranslations translates the description of every pokemon of the batch with the translation
+//service, the result of a pokemon which could not be translated holds its error. Every pokemon counts as a request
+//against the rate limit of the client, the one of the batch request itself included
+func (b *batchService) GetShakespeareanPokemonTranslations(ctx context.Context, request shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse, *api_error.Error) {
+	if len(request.Pokemon) == 0 {
+		return nil, api_error.New(http.StatusBadRequest, api_error.CodeInvalidRequest, "pokemon list cannot be empty")
+	}
+	if len(request.Pokemon) > b.maxItems {
+		return nil, api_error.New(http.StatusBadRequest, api_error.CodeInvalidRequest,
+			fmt.Sprintf("a batch cannot hold more than %d pokemon, got %d", b.maxItems, len(request.Pokemon)))
+	}
+	if wait, ok := ratelimit.Charge(ctx, len(request.Pokemon)-1); !ok {
+		return nil, api_error.New(http.StatusTooManyRequests, api_error.CodeRateLimited,
+			fmt.Sprintf("too many requests, a batch of %d pokemon counts as %d requests", len(request.Pokemon), len(request.Pokemon))).
+			WithRetryAfter(ratelimit.RetryAfterSeconds(wait))
+	}
+
+	workers := b.workers
+	if workers > len(request.Pokemon) {
+		workers = len(request.Pokemon)
+	}
+
+	results := make([]shksprean_pokemon_domain.ShakespeareanPokemonBatchResult, len(request.Pokemon))
+	items := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				results[item] = b.translateItem(ctx, request.Pokemon[item], request.Languages)
+			}
+		}()
+	}
+	for item := range request.Pokemon {
+		items <- item
+	}
+	close(items)
+	wg.Wait()
+
+	return &shksprean_pokemon_domain.ShakespeareanPokemonBatchResponse{Results: results}, nil
+}
+
+func (b *batchService) translateItem(ctx context.Context, item shksprean_pokemon_domain.ShakespeareanPokemonBatchItem, languages []string) shksprean_pokemon_domain.ShakespeareanPokemonBatchResult {
+	select {
+	case b.slots <- struct{}{}:
+		defer func() { <-b.slots }()
+	case <-ctx.Done():
+		fields := api_error.Wrap(ctx.Err(), api_error.StatusClientClosedRequest, api_error.CodeRequestCanceled,
+			"the request was canceled while waiting for a worker").Fields()
+		return shksprean_pokemon_domain.ShakespeareanPokemonBatchResult{Name: item.Name, Status: fields.Status, Error: &fields}
+	}
+
+	response, err := b.translationService.GetShakespeareanPokemonTranslation(ctx, shksprean_pokemon_domain.ShakespeareanPokemonRequest{
+		Name:      item.Name,
+		Style:     item.Style,
+		Version:   item.Version,
+		Languages: languages,
+	})
+	if err != nil {
+		fields := err.Fields()
+		return shksprean_pokemon_domain.ShakespeareanPokemonBatchResult{Name: item.Name, Status: err.Status(), Error: &fields}
+	}
+	return shksprean_pokemon_domain.ShakespeareanPokemonBatchResult{Name: item.Name, Status: http.StatusOK, Response: response}
+}
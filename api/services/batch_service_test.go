@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
+	"shakespearing-pokemon/api/domains/pokemon/pokemon_domain"
+	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
+	"shakespearing-pokemon/api/utils/ratelimit"
+	"sync"
+	"testing"
+	"time"
+)
+
+type translationServiceMock struct {
+	getShakespeareanPokemonTranslationFunc func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error)
+}
+
+func (t *translationServiceMock) GetShakespeareanPokemonTranslation(ctx context.Context, request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+	return t.getShakespeareanPokemonTranslationFunc(request)
+}
+
+func TestGetShakespeareanPokemonTranslations(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		assert.EqualValues(t, []string{"fr", "en"}, request.Languages)
+		if request.Name == "missingno" {
+			return nil, api_error.New(http.StatusServiceUnavailable, api_error.CodeUpstreamUnavailable, "the PokeAPI is unavailable").WithRetryAfter(30)
+		}
+		return &shksprean_pokemon_domain.ShakespeareanPokemonResponse{Name: request.Name, Style: request.Style, Version: request.Version}, nil
+	}
+	service := NewBatchService(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, config.BatchConfig{MaxItems: config.Default().Batch.MaxItems, Workers: 2})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{
+		Pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{
+			{Name: "charizard"},
+			{Name: "missingno"},
+			{Name: "pikachu", Style: "yoda", Version: "red"},
+			{Name: "bulbasaur"},
+			{Name: "mew"},
+		},
+		Languages: []string{"fr", "en"},
+	}
+	response, err := service.GetShakespeareanPokemonTranslations(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, maxRunning)
+	assert.EqualValues(t, 5, len(response.Results))
+	for i, result := range response.Results {
+		assert.EqualValues(t, request.Pokemon[i].Name, result.Name)
+	}
+
+	assert.EqualValues(t, http.StatusOK, response.Results[0].Status)
+	assert.Nil(t, response.Results[0].Error)
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.Results[1].Status)
+	assert.Nil(t, response.Results[1].Response)
+	assert.EqualValues(t, api_error.Fields{Code: api_error.CodeUpstreamUnavailable, Status: http.StatusServiceUnavailable, Message: "the PokeAPI is unavailable", RetryAfter: 30}, *response.Results[1].Error)
+	assert.EqualValues(t, shksprean_pokemon_domain.ShakespeareanPokemonResponse{Name: "pikachu", Style: "yoda", Version: "red"}, *response.Results[2].Response)
+}
+
+func TestGetShakespeareanPokemonTranslationsInvalidBatch(t *testing.T) {
+	service := NewBatchService(&translationServiceMock{}, config.BatchConfig{MaxItems: 2, Workers: config.Default().Batch.Workers})
+
+	testCases := []struct {
+		pokemon []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem
+		message string
+	}{
+		{pokemon: nil, message: "pokemon list cannot be empty"},
+		{pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{{Name: "charizard"}, {Name: "pikachu"}, {Name: "mew"}},
+			message: "a batch cannot hold more than 2 pokemon, got 3"},
+	}
+
+	for _, testCase := range testCases {
+		request := shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{Pokemon: testCase.pokemon}
+		response, err := service.GetShakespeareanPokemonTranslations(context.Background(), request)
+		assert.Nil(t, response)
+		assert.NotNil(t, err)
+		assert.EqualValues(t, http.StatusBadRequest, err.Status())
+		assert.EqualValues(t, testCase.message, err.Message())
+	}
+}
+
+func TestGetShakespeareanPokemonTranslationsRateLimited(t *testing.T) {
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		t.Errorf("no pokemon of a rate limited batch is translated, got %s", request.Name)
+		return nil, nil
+	}
+	service := NewBatchService(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, config.Default().Batch)
+
+	//the batch request itself is already charged, the other pokemon are charged by the service
+	var charged int
+	ctx := ratelimit.WithCharge(context.Background(), func(n int) (time.Duration, bool) {
+		charged = n
+		return 90 * time.Second, false
+	})
+	request := shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{
+		Pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{{Name: "charizard"}, {Name: "pikachu"}, {Name: "mew"}},
+	}
+	response, err := service.GetShakespeareanPokemonTranslations(ctx, request)
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 2, charged)
+	assert.EqualValues(t, http.StatusTooManyRequests, err.Status())
+	assert.EqualValues(t, api_error.CodeRateLimited, err.Code())
+	assert.EqualValues(t, "too many requests, a batch of 3 pokemon counts as 3 requests", err.Message())
+	assert.EqualValues(t, 90, err.RetryAfter())
+}
+
+func TestGetShakespeareanPokemonTranslationsWorkersShared(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return &shksprean_pokemon_domain.ShakespeareanPokemonResponse{Name: request.Name}, nil
+	}
+	service := NewBatchService(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, config.BatchConfig{MaxItems: config.Default().Batch.MaxItems, Workers: 2})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{
+		Pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{{Name: "charizard"}, {Name: "pikachu"}, {Name: "mew"}},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.GetShakespeareanPokemonTranslations(context.Background(), request)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	//the workers are shared by the concurrent batches
+	assert.EqualValues(t, 2, maxRunning)
+}
+
+func TestGetShakespeareanPokemonTranslationsCanceled(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	getShakespeareanPokemonTranslationFunc := func(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+		close(started)
+		<-release
+		return &shksprean_pokemon_domain.ShakespeareanPokemonResponse{Name: request.Name}, nil
+	}
+	service := NewBatchService(&translationServiceMock{getShakespeareanPokemonTranslationFunc: getShakespeareanPokemonTranslationFunc}, config.BatchConfig{MaxItems: config.Default().Batch.MaxItems, Workers: 1})
+
+	//a batch holds the only worker
+	busy := make(chan struct{})
+	go func() {
+		defer close(busy)
+		service.GetShakespeareanPokemonTranslations(context.Background(), shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{
+			Pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{{Name: "charizard"}},
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response, err := service.GetShakespeareanPokemonTranslations(ctx, shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{
+		Pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{{Name: "pikachu"}},
+	})
+	close(release)
+	<-busy
+	assert.Nil(t, err)
+	assert.EqualValues(t, api_error.StatusClientClosedRequest, response.Results[0].Status)
+	assert.EqualValues(t, api_error.CodeRequestCanceled, response.Results[0].Error.Code)
+}
+
+func TestGetShakespeareanPokemonTranslationsInvalidNames(t *testing.T) {
+	var requested []string
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		requested = append(requested, request.Name)
+		return nil, api_error.New(http.StatusNotFound, api_error.CodePokemonNotFound, "pokemon not found")
+	}
+	service := NewBatchService(newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{}), config.BatchConfig{MaxItems: config.Default().Batch.MaxItems, Workers: 1})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonBatchRequest{
+		Pokemon: []shksprean_pokemon_domain.ShakespeareanPokemonBatchItem{{Name: "../pokemon/1"}, {Name: "a?b"}, {Name: "mr-mime"}},
+	}
+	response, err := service.GetShakespeareanPokemonTranslations(context.Background(), request)
+	assert.Nil(t, err)
+	//the invalid names fail on their own and never reach the PokeAPI
+	assert.EqualValues(t, []string{"mr-mime"}, requested)
+	for _, result := range response.Results[:2] {
+		assert.EqualValues(t, http.StatusBadRequest, result.Status, result.Name)
+		assert.EqualValues(t, api_error.CodeInvalidRequest, result.Error.Code, result.Name)
+	}
+	assert.EqualValues(t, http.StatusNotFound, response.Results[2].Status)
+}
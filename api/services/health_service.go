@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/domains/health/health_domain"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"sync"
+	"time"
+)
+
+const (
+	//readinessCacheTTL avoids probing the external apis on every readiness check
+	readinessCacheTTL = 10 * time.Second
+	//probeTimeout stops a hung external api from hanging the readiness checks
+	probeTimeout = 5 * time.Second
+)
+
+//Dependency is an external api whose base url is probed, the base url of FunTranslations does not translate
+//anything so probing it does not consume the translation quota.
+//A dependency whose circuit breaker is open is reported down without being probed. An optional dependency, e.g. one the
+//requests fall back from, is reported but does not make the service unready when down
+type Dependency struct {
+	Name         string
+	Url          func() string
+	BreakerState func() circuitbreaker.State
+	Optional     bool
+}
+
+type healthService struct {
+	client       restclient.ClientInterface
+	mu           sync.Mutex
+	dependencies []Dependency
+	cacheTTL     time.Duration
+	checkedAt    time.Time
+	readiness    *health_domain.ReadinessResponse
+	now          func() time.Time
+}
+
+type HealthServiceInterface interface {
+	GetLiveness() *health_domain.LivenessResponse
+	GetReadiness() *health_domain.ReadinessResponse
+}
+
+//NewHealthService creates a service probing the dependencies through the client
+func NewHealthService(client restclient.ClientInterface, dependencies ...Dependency) HealthServiceInterface {
+	return &healthService{
+		client:       client,
+		dependencies: dependencies,
+		cacheTTL:     readinessCacheTTL,
+		now:          time.Now,
+	}
+}
+
+func (h *healthService) GetLiveness() *health_domain.LivenessResponse {
+	return &health_domain.LivenessResponse{Status: health_domain.StatusUp}
+}
+
+//GetReadiness probes every dependency concurrently, the result is reused for a short time
+func (h *healthService) GetReadiness() *health_domain.ReadinessResponse {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.readiness != nil && h.now().Sub(h.checkedAt) < h.cacheTTL {
+		return h.readiness
+	}
+
+	readiness := &health_domain.ReadinessResponse{
+		Status:       health_domain.StatusUp,
+		Dependencies: make([]health_domain.DependencyStatus, len(h.dependencies)),
+	}
+
+	var wg sync.WaitGroup
+	for i, d := range h.dependencies {
+		wg.Add(1)
+		go func(i int, d Dependency) {
+			defer wg.Done()
+			readiness.Dependencies[i] = h.probe(d)
+		}(i, d)
+	}
+	wg.Wait()
+
+	for _, dependencyStatus := range readiness.Dependencies {
+		if dependencyStatus.Status != health_domain.StatusUp && !dependencyStatus.Optional {
+			readiness.Status = health_domain.StatusDown
+		}
+	}
+
+	h.readiness = readiness
+	h.checkedAt = h.now()
+	return readiness
+}
+
+//probe considers a dependency up when it answers without a server error, e.g. a 404 still proves it is reachable
+func (h *healthService) probe(d Dependency) health_domain.DependencyStatus {
+	status := health_domain.DependencyStatus{Name: d.Name, Status: health_domain.StatusUp, Optional: d.Optional}
+	if d.BreakerState != nil {
+		breakerState := d.BreakerState()
+		status.CircuitBreaker = breakerState.String()
+		if breakerState == circuitbreaker.Open {
+			status.Status = health_domain.StatusDown
+			status.Error = "circuit breaker is open"
+			return status
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	response, err := h.client.Get(ctx, d.Url(), nil)
+	status.LatencyMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		status.Status = health_domain.StatusDown
+		status.Error = err.Error()
+		return status
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		status.Status = health_domain.StatusDown
+		status.Error = fmt.Sprintf("unexpected status code %d", response.StatusCode)
+	}
+	return status
+}
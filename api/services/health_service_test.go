@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"shakespearing-pokemon/api/domains/health/health_domain"
+	"shakespearing-pokemon/api/utils/circuitbreaker"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type getClientMock struct {
+	getRequestFunc func(url string) (*http.Response, error)
+}
+
+func (c *getClientMock) Get(ctx context.Context, url string, headers http.Header) (*http.Response, error) {
+	return c.getRequestFunc(url)
+}
+
+func newTestHealthService(client *getClientMock, now *time.Time) *healthService {
+	closed := func() circuitbreaker.State { return circuitbreaker.Closed }
+	return &healthService{
+		client: client,
+		dependencies: []Dependency{
+			{Name: "pokeapi", Url: func() string { return "http://pokeapi" }, BreakerState: closed},
+			{Name: "funtranslations", Url: func() string { return "http://funtranslations" }, BreakerState: closed, Optional: true},
+		},
+		cacheTTL: time.Minute,
+		now:      func() time.Time { return *now },
+	}
+}
+
+func TestGetLiveness(t *testing.T) {
+	assert.EqualValues(t, health_domain.StatusUp, NewHealthService(&getClientMock{}).GetLiveness().Status)
+}
+
+func TestGetReadiness(t *testing.T) {
+	getRequestFunc := func(url string) (*http.Response, error) {
+		if url == "http://funtranslations" {
+			return nil, errors.New("connection refused")
+		}
+		//a not found still proves the dependency is reachable
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(strings.NewReader("Not Found")),
+		}, nil
+	}
+
+	now := time.Now()
+	readiness := newTestHealthService(&getClientMock{getRequestFunc: getRequestFunc}, &now).GetReadiness()
+	//the translations fall back to other engines, the service stays ready
+	assert.EqualValues(t, health_domain.StatusUp, readiness.Status)
+	assert.EqualValues(t, 2, len(readiness.Dependencies))
+	assert.EqualValues(t, "pokeapi", readiness.Dependencies[0].Name)
+	assert.EqualValues(t, health_domain.StatusUp, readiness.Dependencies[0].Status)
+	assert.EqualValues(t, "funtranslations", readiness.Dependencies[1].Name)
+	assert.EqualValues(t, health_domain.StatusDown, readiness.Dependencies[1].Status)
+	assert.True(t, readiness.Dependencies[1].Optional)
+	assert.EqualValues(t, "connection refused", readiness.Dependencies[1].Error)
+}
+
+func TestGetReadinessServerError(t *testing.T) {
+	getRequestFunc := func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusBadGateway,
+			Body:       ioutil.NopCloser(strings.NewReader("Bad Gateway")),
+		}, nil
+	}
+
+	now := time.Now()
+	readiness := newTestHealthService(&getClientMock{getRequestFunc: getRequestFunc}, &now).GetReadiness()
+	assert.False(t, readiness.Ready())
+	assert.EqualValues(t, "unexpected status code 502", readiness.Dependencies[0].Error)
+}
+
+func TestGetReadinessCircuitBreakerOpen(t *testing.T) {
+	var calls int32
+	getRequestFunc := func(url string) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+
+	now := time.Now()
+	service := newTestHealthService(&getClientMock{getRequestFunc: getRequestFunc}, &now)
+	service.dependencies[1].BreakerState = func() circuitbreaker.State { return circuitbreaker.Open }
+
+	readiness := service.GetReadiness()
+	assert.True(t, readiness.Ready())
+	assert.EqualValues(t, health_domain.StatusUp, readiness.Dependencies[0].Status)
+	assert.EqualValues(t, "closed", readiness.Dependencies[0].CircuitBreaker)
+	assert.EqualValues(t, health_domain.StatusDown, readiness.Dependencies[1].Status)
+	assert.EqualValues(t, "open", readiness.Dependencies[1].CircuitBreaker)
+	assert.EqualValues(t, "circuit breaker is open", readiness.Dependencies[1].Error)
+	//the dependency whose circuit breaker is open is not probed
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	//the PokeAPI is needed by every request
+	now = now.Add(2 * time.Minute)
+	service.dependencies[0].BreakerState = func() circuitbreaker.State { return circuitbreaker.Open }
+	readiness = service.GetReadiness()
+	assert.False(t, readiness.Ready())
+	assert.EqualValues(t, "circuit breaker is open", readiness.Dependencies[0].Error)
+}
+
+func TestGetReadinessIsCached(t *testing.T) {
+	var calls int32
+	getRequestFunc := func(url string) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+
+	now := time.Now()
+	service := newTestHealthService(&getClientMock{getRequestFunc: getRequestFunc}, &now)
+	assert.True(t, service.GetReadiness().Ready())
+	assert.True(t, service.GetReadiness().Ready())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+
+	now = now.Add(2 * time.Minute)
+	assert.True(t, service.GetReadiness().Ready())
+	assert.EqualValues(t, 4, atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
+	"shakespearing-pokemon/api/providers/local_translation_provider"
+	"shakespearing-pokemon/api/providers/translation_provider"
+)
+
+//TranslationEngine is a step of the translation fallback chain, the translations of a degraded engine are not as good
+//as the up to date ones of the FunTranslations API
+type TranslationEngine struct {
+	Name       string
+	Translator translation_provider.Translator
+	Degraded   bool
+}
+
+//NewTranslationChain chains the translation engines in the order of fallback, funTranslations being the translator
+//calling the FunTranslations API and stale the one serving the cached translations, even the expired ones
+func NewTranslationChain(fallback []string, funTranslations translation_provider.Translator, stale translation_provider.Translator) []TranslationEngine {
+	engines := map[string]TranslationEngine{
+		shksprean_pokemon_domain.EngineFunTranslations: {
+			Name:       shksprean_pokemon_domain.EngineFunTranslations,
+			Translator: funTranslations,
+		},
+		shksprean_pokemon_domain.EngineCache: {
+			Name:       shksprean_pokemon_domain.EngineCache,
+			Translator: stale,
+			Degraded:   true,
+		},
+		shksprean_pokemon_domain.EngineLocal: {
+			Name:       shksprean_pokemon_domain.EngineLocal,
+			Translator: local_translation_provider.NewLocalTranslationProvider(),
+			Degraded:   true,
+		},
+		shksprean_pokemon_domain.EngineOriginal: {
+			Name:       shksprean_pokemon_domain.EngineOriginal,
+			Translator: local_translation_provider.NewUntranslatedProvider(),
+			Degraded:   true,
+		},
+	}
+	chain := make([]TranslationEngine, 0, len(fallback))
+	for _, name := range fallback {
+		chain = append(chain, engines[name])
+	}
+	return chain
+}
@@ -1,68 +1,198 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"math/rand"
 	"net/http"
+	"regexp"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/pokemon/pokemon_domain"
 	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
-	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_error"
 	"shakespearing-pokemon/api/domains/translation/translation_domain"
+	"shakespearing-pokemon/api/logger"
 	"shakespearing-pokemon/api/providers/pokemon_provider"
 	"shakespearing-pokemon/api/providers/translation_provider"
+	"shakespearing-pokemon/api/utils/flavortext"
+	"shakespearing-pokemon/api/utils/language"
+	"strings"
+	"sync"
+	"time"
 )
 
-type translationService struct{}
+type translationService struct {
+	pokemonProvider pokemon_provider.PokemonInfoProvider
+	//chain holds the engines tried in order until one of them translates the description
+	chain []TranslationEngine
+	//randomIndex picks the description of the random version
+	randomIndex func(n int) int
+}
 
-type translationServiceInterface interface {
-	GetShakespeareanPokemonTranslation(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, shksprean_pokemon_error.ShkspreanPokemonErrorInterface)
+type TranslationServiceInterface interface {
+	GetShakespeareanPokemonTranslation(ctx context.Context, request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error)
 }
 
-var (
-	TranslationService translationServiceInterface = &translationService{}
-)
+//speciesName matches the names and ids of the PokeAPI species, e.g. charizard, mr-mime or 6
+var speciesName = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
 
-func (t *translationService) GetShakespeareanPokemonTranslation(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, shksprean_pokemon_error.ShkspreanPokemonErrorInterface) {
-	request, err := validateRequestFields(request)
-	if err != nil {
-		return nil, shksprean_pokemon_error.New(http.StatusBadRequest, err.Error())
+//NewTranslationService creates a service describing the pokemon of the provider, the engines of the chain are tried in
+//order until one of them translates the description
+func NewTranslationService(pokemonProvider pokemon_provider.PokemonInfoProvider, chain []TranslationEngine) TranslationServiceInterface {
+	return &translationService{pokemonProvider: pokemonProvider, chain: chain, randomIndex: newRandomIndex()}
+}
+
+//newRandomIndex returns a random index in [0, n), its source is seeded so that the picks differ between restarts
+func newRandomIndex() func(n int) int {
+	var mu sync.Mutex
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return func(n int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return random.Intn(n)
+	}
+}
+
+func (t *translationService) GetShakespeareanPokemonTranslation(ctx context.Context, request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (*shksprean_pokemon_domain.ShakespeareanPokemonResponse, *api_error.Error) {
+	request, errorResp := validateRequestFields(request)
+	if errorResp != nil {
+		return nil, errorResp
 	}
 
 	pokemonInfoReq := pokemon_domain.PokemonInfoRequest{Name: request.Name}
 
 	//get description from pokemon provider
-	pokemonInfoResp, pokemonErrorResp := pokemon_provider.PokemonProvider.GetPokemonInfo(pokemonInfoReq)
+	pokemonInfoResp, pokemonErrorResp := t.pokemonProvider.GetPokemonInfo(ctx, pokemonInfoReq)
 	if pokemonErrorResp != nil {
-		return nil, shksprean_pokemon_error.New(pokemonErrorResp.Status(), pokemonErrorResp.Message())
+		return nil, pokemonErrorResp
+	}
+
+	//English is the last resort, whatever the preferences
+	preferences := append(append([]string{}, request.Languages...), translation_domain.DefaultLanguage)
+	sourceLanguage, ok := language.Match(preferences, pokemonInfoResp.Description.Languages())
+	if !ok {
+		sourceLanguage = translation_domain.DefaultLanguage
 	}
 
-	translationRequest := translation_domain.TranslationRequest{}
+	//a pokemon without description is reported as such rather than spending the translation quota on an empty text
+	descriptions := pokemonInfoResp.Description.InLanguage(sourceLanguage)
+	description, err := selectDescription(descriptions, request.Version, t.randomIndex)
+	switch err {
+	case pokemon_domain.ErrDescriptionUnavailable:
+		return nil, api_error.New(http.StatusNotFound, api_error.CodeDescriptionUnavailable,
+			descriptionUnavailableMessage(request, sourceLanguage, pokemonInfoResp.Description.Languages()))
+	case pokemon_domain.ErrVersionUnavailable:
+		return nil, api_error.New(http.StatusNotFound, api_error.CodeVersionUnavailable,
+			versionNotFoundMessage(request, descriptions.Versions()))
+	}
+	//the description is laid out for the game screens, the translators expect a plain sentence. Once laid out, a text
+	//made of soft hyphens or other layout characters only is as empty as a blank one
+	translationRequest := translation_domain.TranslationRequest{
+		Text:     flavortext.Normalize(description.Text, request.Name),
+		Style:    request.Style,
+		Language: sourceLanguage,
+	}
+	if translationRequest.Text == "" {
+		return nil, api_error.New(http.StatusNotFound, api_error.CodeDescriptionUnavailable,
+			descriptionUnavailableMessage(request, sourceLanguage, otherLanguages(pokemonInfoResp.Description.Languages(), sourceLanguage)))
+	}
+
+	//get translation from the translation provider
+	//the FunTranslations API being down or out of quota should not deprive the client of a description, the engines
+	//are tried in order and the error of the first one is returned only when none of them could translate
+	var firstErrorResp *api_error.Error
+	for _, engine := range t.chain {
+		translationResp, translationErrorResp := engine.Translator.Translate(ctx, translationRequest)
+		if translationErrorResp == nil {
+			//generate the client response
+			return &shksprean_pokemon_domain.ShakespeareanPokemonResponse{
+				Name:        request.Name,
+				Translation: translationResp.Content.Translation,
+				Style:       request.Style,
+				Version:     description.Version.Name,
+				Language:    sourceLanguage,
+				Engine:      engine.Name,
+				Degraded:    engine.Degraded,
+			}, nil
+		}
 
-	//get the most recent description form the pokemon info response
-	for i := len(pokemonInfoResp.Description) - 1; i >= 0; i-- {
-		if pokemonInfoResp.Description[i].Language.Name == "en" {
-			translationRequest.Text = pokemonInfoResp.Description[i].Text
-			break
+		logger.FromContext(ctx).Warn("translation engine failed", logger.Fields{
+			"engine": engine.Name,
+			"status": translationErrorResp.Status(),
+			"error":  translationErrorResp.Message(),
+		})
+		if firstErrorResp == nil {
+			firstErrorResp = translationErrorResp
 		}
 	}
+	return nil, firstErrorResp
+}
 
-	//get translation from Shakespearean translation provider
-	translationResp, translationErrorResp := translation_provider.TranslationProvider.GetShakespeareanTranslation(translationRequest)
-	if translationErrorResp != nil {
-		return nil, shksprean_pokemon_error.New(translationErrorResp.Status(), translationErrorResp.Message())
+func validateRequestFields(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (shksprean_pokemon_domain.ShakespeareanPokemonRequest, *api_error.Error) {
+	if request.Name == "" {
+		return shksprean_pokemon_domain.ShakespeareanPokemonRequest{}, api_error.New(http.StatusBadRequest, api_error.CodeInvalidRequest, "name field cannot be empty")
+	}
+	if !speciesName.MatchString(request.Name) {
+		return shksprean_pokemon_domain.ShakespeareanPokemonRequest{}, api_error.New(http.StatusBadRequest, api_error.CodeInvalidRequest,
+			fmt.Sprintf("invalid pokemon name %q, expected letters, digits and hyphens", request.Name))
+	}
+	if request.Style == "" {
+		request.Style = translation_domain.DefaultStyle
 	}
+	request.Version = strings.ToLower(request.Version)
+	if request.Version == "" {
+		request.Version = shksprean_pokemon_domain.VersionLatest
+	}
+	if !translation_provider.IsSupportedStyle(request.Style) {
+		return shksprean_pokemon_domain.ShakespeareanPokemonRequest{}, api_error.New(http.StatusBadRequest, api_error.CodeUnknownStyle,
+			fmt.Sprintf("unknown translation style %q, expected one of %s", request.Style, strings.Join(translation_provider.Styles(), ", ")))
+	}
+	return request, nil
+}
 
-	response := &shksprean_pokemon_domain.ShakespeareanPokemonResponse{
-		Name:        request.Name,
-		Translation: translationResp.Content.Translation,
+//selectDescription picks the description of the requested version among the descriptions listed from the earliest to
+//the latest version, randomIndex picks the description of the random version
+func selectDescription(descriptions pokemon_domain.FlavourTextList, version string, randomIndex func(n int) int) (pokemon_domain.FlavourText, error) {
+	if len(descriptions) == 0 {
+		return pokemon_domain.FlavourText{}, pokemon_domain.ErrDescriptionUnavailable
 	}
 
-	//generate the client response
-	return response, nil
+	switch version {
+	case shksprean_pokemon_domain.VersionLatest:
+		return descriptions[len(descriptions)-1], nil
+	case shksprean_pokemon_domain.VersionEarliest:
+		return descriptions[0], nil
+	case shksprean_pokemon_domain.VersionRandom:
+		return descriptions[randomIndex(len(descriptions))], nil
+	}
+
+	for _, description := range descriptions {
+		if description.Version.Name == version {
+			return description, nil
+		}
+	}
+	return pokemon_domain.FlavourText{}, pokemon_domain.ErrVersionUnavailable
 }
 
-func validateRequestFields(request shksprean_pokemon_domain.ShakespeareanPokemonRequest) (shksprean_pokemon_domain.ShakespeareanPokemonRequest, error) {
-	if request.Name == "" {
-		return shksprean_pokemon_domain.ShakespeareanPokemonRequest{}, errors.New("name field cannot be empty")
+func descriptionUnavailableMessage(request shksprean_pokemon_domain.ShakespeareanPokemonRequest, language string, languages []string) string {
+	if len(languages) == 0 {
+		return fmt.Sprintf("%s has no description in %s, nor in any other language", request.Name, language)
 	}
-	return request, nil
+	return fmt.Sprintf("%s has no description in %s, available languages are %s", request.Name, language, strings.Join(languages, ", "))
+}
+
+func otherLanguages(languages []string, language string) []string {
+	others := make([]string, 0, len(languages))
+	for _, l := range languages {
+		if l != language {
+			others = append(others, l)
+		}
+	}
+	return others
+}
+
+func versionNotFoundMessage(request shksprean_pokemon_domain.ShakespeareanPokemonRequest, versions []string) string {
+	if len(versions) == 0 {
+		return fmt.Sprintf("%s has no description for version %q, nor for any other version", request.Name, request.Version)
+	}
+	return fmt.Sprintf("%s has no description for version %q, available versions are %s", request.Name, request.Version, strings.Join(versions, ", "))
 }
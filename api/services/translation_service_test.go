@@ -1,33 +1,55 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"shakespearing-pokemon/api/caches/translation_cache"
+	"shakespearing-pokemon/api/clients/restclient"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/domains/errors/api_error"
 	"shakespearing-pokemon/api/domains/pokemon/pokemon_domain"
-	"shakespearing-pokemon/api/domains/pokemon/pokemon_error"
 	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_domain"
-	"shakespearing-pokemon/api/domains/shksprean_pokemon_domain/shksprean_pokemon_error"
 	"shakespearing-pokemon/api/domains/translation/translation_domain"
-	"shakespearing-pokemon/api/domains/translation/translation_error"
+	"shakespearing-pokemon/api/metrics"
+	"shakespearing-pokemon/api/providers/local_translation_provider"
 	"shakespearing-pokemon/api/providers/pokemon_provider"
 	"shakespearing-pokemon/api/providers/translation_provider"
 	"testing"
 )
 
-var (
-	getPokemonInfo              func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *pokemon_error.PokemonError)
-	getShakespeareanTranslation func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *translation_error.TranslationError)
-)
+type getPokemonProviderMock struct {
+	getPokemonInfo func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error)
+}
+
+type getTranslationProviderMock struct {
+	getShakespeareanTranslation func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error)
+}
 
-type getPokemonProviderMock struct{}
-type getTranslationProviderMock struct{}
+func (p *getPokemonProviderMock) GetPokemonInfo(ctx context.Context, request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+	return p.getPokemonInfo(request)
+}
+
+func (s *getTranslationProviderMock) Translate(ctx context.Context, request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+	return s.getShakespeareanTranslation(request)
+}
 
-func (p *getPokemonProviderMock) GetPokemonInfo(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *pokemon_error.PokemonError) {
-	return getPokemonInfo(request)
+//newStaleProviderMock mocks the translation cache of the chain, it has no translation cached
+func newStaleProviderMock() *getTranslationProviderMock {
+	return &getTranslationProviderMock{
+		getShakespeareanTranslation: func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+			return nil, api_error.New(http.StatusNotFound, api_error.CodeTranslationUnavailable, "no cached translation")
+		},
+	}
 }
 
-func (s *getTranslationProviderMock) GetShakespeareanTranslation(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *translation_error.TranslationError) {
-	return getShakespeareanTranslation(request)
+//newTestService describes the pokemon of the mocked provider with the default chain, the mocked translator is tried
+//before the empty cache and the local ones
+func newTestService(pokemonProvider *getPokemonProviderMock, translationProvider *getTranslationProviderMock) TranslationServiceInterface {
+	return NewTranslationService(pokemonProvider,
+		NewTranslationChain(config.Default().Translation.Fallback, translationProvider, newStaleProviderMock()))
 }
 
 func TestGetShakespeareanPokemonTranslationSuccess(t *testing.T) {
@@ -64,23 +86,115 @@ func TestGetShakespeareanPokemonTranslationSuccess(t *testing.T) {
 		Translation: "Vestibulum lacinia arcu eget nulla.",
 	}
 
-	getPokemonInfo = func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *pokemon_error.PokemonError) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
 		return &mockPokemonInfoResp, nil
 	}
 
-	getShakespeareanTranslation = func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *translation_error.TranslationError) {
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
 		return &mockTranslationResp, nil
 	}
 
-	translation_provider.TranslationProvider = &getTranslationProviderMock{}
-	pokemon_provider.PokemonProvider = &getPokemonProviderMock{}
+	service := newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation})
 
 	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard"}
-	actualResponse, err := TranslationService.GetShakespeareanPokemonTranslation(request)
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
 	assert.Nil(t, err)
 	assert.NotNil(t, actualResponse)
 	assert.EqualValues(t, expectedResponse.Name, actualResponse.Name)
 	assert.EqualValues(t, expectedResponse.Translation, actualResponse.Translation)
+	assert.EqualValues(t, "shakespeare", actualResponse.Style)
+	assert.EqualValues(t, "funtranslations", actualResponse.Engine)
+	assert.False(t, actualResponse.Degraded)
+}
+
+func TestGetShakespeareanPokemonTranslationLocalFallback(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{
+			Name:        "charizard",
+			Description: pokemon_domain.FlavourTextList{{Text: "It is hot.", Language: pokemon_domain.LanguageFields{Name: "en"}}},
+		}, nil
+	}
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		return nil, api_error.New(http.StatusTooManyRequests, api_error.CodeTranslationQuotaExceeded, "the FunTranslations API quota is spent").WithRetryAfter(60)
+	}
+
+	service := newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard"}
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "'Tis hot.", actualResponse.Translation)
+	assert.EqualValues(t, "local", actualResponse.Engine)
+	assert.True(t, actualResponse.Degraded)
+
+	//the local translator only speaks Shakespearean English, the description is left untranslated
+	request = shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Style: "yoda"}
+	actualResponse, err = service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "It is hot.", actualResponse.Translation)
+	assert.EqualValues(t, "original", actualResponse.Engine)
+	assert.True(t, actualResponse.Degraded)
+}
+
+func TestGetShakespeareanPokemonTranslationChain(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{
+			Name:        "charizard",
+			Description: pokemon_domain.FlavourTextList{{Text: "It is hot.", Language: pokemon_domain.LanguageFields{Name: "en"}}},
+		}, nil
+	}
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		return nil, api_error.New(http.StatusTooManyRequests, api_error.CodeTranslationQuotaExceeded, "the FunTranslations API quota is spent").WithRetryAfter(60)
+	}
+	service := NewTranslationService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, []TranslationEngine{
+		{Name: "funtranslations", Translator: &getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation}},
+		{Name: "local", Translator: local_translation_provider.NewLocalTranslationProvider(), Degraded: true},
+	})
+
+	//the error of the first engine is returned when no engine could translate
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Style: "yoda"}
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusTooManyRequests, err.Status())
+	assert.EqualValues(t, api_error.CodeTranslationQuotaExceeded, err.Code())
+	assert.EqualValues(t, "the FunTranslations API quota is spent", err.Message())
+	assert.EqualValues(t, 60, err.RetryAfter())
+}
+
+func TestGetShakespeareanPokemonTranslationStyle(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{
+			Name:        "charizard",
+			Description: pokemon_domain.FlavourTextList{{Text: "Spits fire.", Language: pokemon_domain.LanguageFields{Name: "en"}}},
+		}, nil
+	}
+
+	var translationRequest translation_domain.TranslationRequest
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		translationRequest = request
+		return &translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: "Fire, spits it."}}, nil
+	}
+
+	service := newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Style: "yoda"}
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, translation_domain.TranslationRequest{Text: "Spits fire.", Style: "yoda", Language: "en"}, translationRequest)
+	assert.EqualValues(t, "Fire, spits it.", actualResponse.Translation)
+	assert.EqualValues(t, "yoda", actualResponse.Style)
+}
+
+func TestGetShakespeareanPokemonTranslationWithUnknownStyle(t *testing.T) {
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Style: "klingon"}
+
+	actualResponse, err := newTestService(&getPokemonProviderMock{}, &getTranslationProviderMock{}).GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	assert.EqualValues(t, `unknown translation style "klingon", expected one of cockney, gungan, minion, old-english, `+
+		`pirate, shakespeare, sith, valley-speak, yoda`, err.Message())
 }
 
 func TestGetShakespeareanPokemonTranslationSuccessIntegration(t *testing.T) {
@@ -92,7 +206,19 @@ func TestGetShakespeareanPokemonTranslationSuccessIntegration(t *testing.T) {
 		Name: "charizard",
 	}
 
-	actualResponse, err := TranslationService.GetShakespeareanPokemonTranslation(request)
+	client := restclient.New()
+	cfg := config.Default()
+	//the translations are cached in memory only
+	translationCache, cacheErr := translation_cache.New(translation_cache.Config{
+		TTL:        cfg.Cache.Translation.TTL.Duration,
+		StaleTTL:   cfg.Cache.Translation.StaleTTL.Duration,
+		MaxEntries: cfg.Cache.Translation.MaxEntries,
+	})
+	assert.Nil(t, cacheErr)
+	service := NewTranslationService(pokemon_provider.New(client, cfg.PokeAPI, metrics.NewRegistry()),
+		NewTranslationChain(cfg.Translation.Fallback, translation_provider.New(client, cfg.Translation, metrics.NewRegistry()),
+			translation_cache.NewStaleProvider(translationCache)))
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
 	assert.Nil(t, err)
 	assert.NotNil(t, actualResponse)
 	if actualResponse.Translation == "" {
@@ -101,17 +227,284 @@ func TestGetShakespeareanPokemonTranslationSuccessIntegration(t *testing.T) {
 	assert.EqualValues(t, request.Name, actualResponse.Name)
 }
 
+//Checks that the failures of fake external apis reach the client with their status, code and Retry-After
+func TestGetShakespeareanPokemonTranslationUpstreamFailures(t *testing.T) {
+	species := `{"name": "charizard", "flavor_text_entries": [{"flavor_text": "Spits fire.", "language": {"name": "en"}, "version": {"name": "red"}}]}`
+	testCases := []struct {
+		name               string
+		pokeApi            http.HandlerFunc
+		funTranslations    http.HandlerFunc
+		expectedStatus     int
+		expectedCode       api_error.Code
+		expectedRetryAfter int
+	}{
+		{
+			name: "pokeapi outage",
+			pokeApi: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedCode:       api_error.CodeUpstreamUnavailable,
+			expectedRetryAfter: 120,
+		},
+		{
+			name:           "pokeapi server error",
+			pokeApi:        func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   api_error.CodeUpstreamError,
+		},
+		{
+			name:           "unknown pokemon",
+			pokeApi:        func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   api_error.CodePokemonNotFound,
+		},
+		{
+			name:    "translation quota spent",
+			pokeApi: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(species)) },
+			funTranslations: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "1800")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       api_error.CodeTranslationQuotaExceeded,
+			expectedRetryAfter: 1800,
+		},
+		{
+			name:            "undecodable translation",
+			pokeApi:         func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(species)) },
+			funTranslations: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("<html>FunTranslations</html>")) },
+			expectedStatus:  http.StatusBadGateway,
+			expectedCode:    api_error.CodeUpstreamBadResponse,
+		},
+	}
+
+	for _, testCase := range testCases {
+		pokeApi := httptest.NewServer(testCase.pokeApi)
+		funTranslations := httptest.NewServer(testCase.funTranslations)
+		client := restclient.New()
+		cfg := config.Default()
+		cfg.PokeAPI.BaseUrl = pokeApi.URL
+		cfg.PokeAPI.Retry = config.RetryConfig{}
+		cfg.Translation.BaseUrl = funTranslations.URL
+		cfg.Translation.Retry = config.RetryConfig{}
+		//only FunTranslations is tried, the local engines would hide its failures
+		service := NewTranslationService(pokemon_provider.New(client, cfg.PokeAPI, metrics.NewRegistry()), []TranslationEngine{
+			{Name: "funtranslations", Translator: translation_provider.New(client, cfg.Translation, metrics.NewRegistry())},
+		})
+
+		actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard"})
+		pokeApi.Close()
+		funTranslations.Close()
+		assert.Nil(t, actualResponse, testCase.name)
+		if assert.NotNil(t, err, testCase.name) {
+			assert.EqualValues(t, testCase.expectedStatus, err.Status(), testCase.name)
+			assert.EqualValues(t, testCase.expectedCode, err.Code(), testCase.name)
+			assert.EqualValues(t, testCase.expectedRetryAfter, err.RetryAfter(), testCase.name)
+		}
+	}
+}
+
 func TestGetShakespeareanPokemonTranslationWithEmptyName(t *testing.T) {
-	expectedError := shksprean_pokemon_error.ShkspreanPokemonError{
-		Error: shksprean_pokemon_error.ErrorFields{
-			Code:    http.StatusBadRequest,
-			Message: "name field cannot be empty",
-		}}
+	expectedError := api_error.New(http.StatusBadRequest, api_error.CodeInvalidRequest, "name field cannot be empty")
 	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: ""}
 
-	actualResponse, err := TranslationService.GetShakespeareanPokemonTranslation(request)
+	actualResponse, err := newTestService(&getPokemonProviderMock{}, &getTranslationProviderMock{}).GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, expectedError, err)
+}
+
+func TestGetShakespeareanPokemonTranslationWithInvalidName(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		t.Errorf("the info of an invalid name is not requested, got %q", request.Name)
+		return nil, nil
+	}
+
+	for _, name := range []string{"../pokemon/1", "a?b", "mr mime", "charizard/", "-mew", "ho--oh"} {
+		request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: name}
+		actualResponse, err := newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{}).GetShakespeareanPokemonTranslation(context.Background(), request)
+		assert.Nil(t, actualResponse, name)
+		if assert.NotNil(t, err, name) {
+			assert.EqualValues(t, http.StatusBadRequest, err.Status(), name)
+			assert.EqualValues(t, api_error.CodeInvalidRequest, err.Code(), name)
+			assert.EqualValues(t, fmt.Sprintf("invalid pokemon name %q, expected letters, digits and hyphens", name), err.Message(), name)
+		}
+	}
+}
+
+func TestSelectDescription(t *testing.T) {
+	randomIndex := func(n int) int { return n - 2 }
+
+	descriptions := pokemon_domain.FlavourTextList{
+		{Text: "Spits fire.", Version: pokemon_domain.VersionFields{Name: "red"}},
+		{Text: "Flies around.", Version: pokemon_domain.VersionFields{Name: "gold"}},
+		{Text: "Breathes fire.", Version: pokemon_domain.VersionFields{Name: "x"}},
+	}
+
+	testCases := []struct {
+		version      string
+		descriptions pokemon_domain.FlavourTextList
+		text         string
+		err          error
+	}{
+		{version: "latest", descriptions: descriptions, text: "Breathes fire."},
+		{version: "earliest", descriptions: descriptions, text: "Spits fire."},
+		{version: "random", descriptions: descriptions, text: "Flies around."},
+		{version: "gold", descriptions: descriptions, text: "Flies around."},
+		{version: "sapphire", descriptions: descriptions, err: pokemon_domain.ErrVersionUnavailable},
+		{version: "latest", descriptions: nil, err: pokemon_domain.ErrDescriptionUnavailable},
+		{version: "red", descriptions: nil, err: pokemon_domain.ErrDescriptionUnavailable},
+	}
+
+	for _, testCase := range testCases {
+		description, err := selectDescription(testCase.descriptions, testCase.version, randomIndex)
+		assert.EqualValues(t, testCase.err, err, testCase.version)
+		assert.EqualValues(t, testCase.text, description.Text, testCase.version)
+	}
+}
+
+func TestGetShakespeareanPokemonTranslationVersion(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{
+			Name: "charizard",
+			Description: pokemon_domain.FlavourTextList{
+				{Text: "Spits fire.", Language: pokemon_domain.LanguageFields{Name: "en"}, Version: pokemon_domain.VersionFields{Name: "red"}},
+				{Text: "Crache du feu.", Language: pokemon_domain.LanguageFields{Name: "fr"}, Version: pokemon_domain.VersionFields{Name: "x"}},
+				{Text: "Breathes fire.", Language: pokemon_domain.LanguageFields{Name: "en"}, Version: pokemon_domain.VersionFields{Name: "y"}},
+			},
+		}, nil
+	}
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		return &translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: request.Text}}, nil
+	}
+
+	service := newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Version: "RED"}
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Spits fire.", actualResponse.Translation)
+	assert.EqualValues(t, "red", actualResponse.Version)
+
+	request = shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard"}
+	actualResponse, err = service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "y", actualResponse.Version)
+
+	//the versions which only have descriptions in other languages are not available
+	request = shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Version: "x"}
+	actualResponse, err = service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+	assert.EqualValues(t, `charizard has no description for version "x", available versions are red, y`, err.Message())
+	assert.EqualValues(t, api_error.CodeVersionUnavailable, err.Code())
+}
+
+//Checks that a pokemon without description is reported without calling the translator
+func TestGetShakespeareanPokemonTranslationDescriptionUnavailable(t *testing.T) {
+	description := pokemon_domain.FlavourTextList{
+		{Text: " \n", Language: pokemon_domain.LanguageFields{Name: "en"}, Version: pokemon_domain.VersionFields{Name: "x"}},
+	}
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{Name: "missingno", Description: description}, nil
+	}
+	translated := false
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		translated = true
+		return &translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: request.Text}}, nil
+	}
+
+	service := newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "missingno", Version: "red"}
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.NotNil(t, err)
+	assert.False(t, translated)
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+	assert.EqualValues(t, api_error.CodeDescriptionUnavailable, err.Code())
+	assert.EqualValues(t, "missingno has no description in en, nor in any other language", err.Message())
+
+	description = append(description, pokemon_domain.FlavourText{Text: "Unbekannt.", Language: pokemon_domain.LanguageFields{Name: "de"}})
+	request = shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "missingno", Languages: []string{"fr"}}
+	_, err = service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.NotNil(t, err)
+	assert.False(t, translated)
+	assert.EqualValues(t, "missingno has no description in en, available languages are de", err.Message())
+
+	//soft hyphens are dropped when the text is laid out, leaving nothing to translate
+	description = pokemon_domain.FlavourTextList{
+		{Text: "\u00ad\n\u00ad", Language: pokemon_domain.LanguageFields{Name: "en"}, Version: pokemon_domain.VersionFields{Name: "x"}},
+	}
+	request = shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "missingno"}
+	_, err = service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.NotNil(t, err)
+	assert.False(t, translated)
+	assert.EqualValues(t, api_error.CodeDescriptionUnavailable, err.Code())
+	assert.EqualValues(t, "missingno has no description in en, nor in any other language", err.Message())
+}
+
+func TestGetShakespeareanPokemonTranslationLanguage(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{
+			Name: "charizard",
+			Description: pokemon_domain.FlavourTextList{
+				{Text: "Spits fire.", Language: pokemon_domain.LanguageFields{Name: "en"}, Version: pokemon_domain.VersionFields{Name: "x"}},
+				{Text: "Crache du feu.", Language: pokemon_domain.LanguageFields{Name: "fr"}, Version: pokemon_domain.VersionFields{Name: "x"}},
+			},
+		}, nil
+	}
+	var translationRequests []translation_domain.TranslationRequest
+	getShakespeareanTranslation := func(request translation_domain.TranslationRequest) (*translation_domain.TranslationResponse, *api_error.Error) {
+		translationRequests = append(translationRequests, request)
+		return &translation_domain.TranslationResponse{Content: translation_domain.ContentFields{Translation: request.Text}}, nil
+	}
+
+	service := newTestService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, &getTranslationProviderMock{getShakespeareanTranslation: getShakespeareanTranslation})
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Languages: []string{"de", "fr-CH", "en"}}
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Crache du feu.", actualResponse.Translation)
+	assert.EqualValues(t, "fr", actualResponse.Language)
+	assert.EqualValues(t, "fr", translationRequests[0].Language)
+
+	//English is the last resort
+	request = shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Languages: []string{"ja"}}
+	actualResponse, err = service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Spits fire.", actualResponse.Translation)
+	assert.EqualValues(t, "en", actualResponse.Language)
+}
+
+//Checks that a description the translators cannot translate is left untranslated, or rejected when the chain has no
+//engine returning the original description
+func TestGetShakespeareanPokemonTranslationUntranslatableLanguage(t *testing.T) {
+	getPokemonInfo := func(request pokemon_domain.PokemonInfoRequest) (*pokemon_domain.PokemonInfoResponse, *api_error.Error) {
+		return &pokemon_domain.PokemonInfoResponse{
+			Name:        "charizard",
+			Description: pokemon_domain.FlavourTextList{{Text: "Crache du feu.", Language: pokemon_domain.LanguageFields{Name: "fr"}}},
+		}, nil
+	}
+	service := NewTranslationService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, NewTranslationChain(config.Default().Translation.Fallback, local_translation_provider.NewLocalTranslationProvider(), newStaleProviderMock()))
+
+	request := shksprean_pokemon_domain.ShakespeareanPokemonRequest{Name: "charizard", Languages: []string{"fr"}}
+	actualResponse, err := service.GetShakespeareanPokemonTranslation(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Crache du feu.", actualResponse.Translation)
+	assert.EqualValues(t, "original", actualResponse.Engine)
+	assert.True(t, actualResponse.Degraded)
+
+	service = NewTranslationService(&getPokemonProviderMock{getPokemonInfo: getPokemonInfo}, []TranslationEngine{
+		{Name: "local", Translator: local_translation_provider.NewLocalTranslationProvider(), Degraded: true},
+	})
+
+	actualResponse, err = service.GetShakespeareanPokemonTranslation(context.Background(), request)
 	assert.Nil(t, actualResponse)
 	assert.NotNil(t, err)
-	assert.EqualValues(t, expectedError.Status(), err.Status())
-	assert.EqualValues(t, expectedError.Message(), err.Message())
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, `the local translator only translates English descriptions, not "fr" ones`, err.Message())
 }
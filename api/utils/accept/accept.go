@@ -0,0 +1,56 @@
+package accept
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Preference is a value listed by an Accept style header, e.g. a media range or a language tag, with its quality
+type Preference struct {
+	Value   string
+	Quality float64
+}
+
+//Parse returns the values of an Accept style header, such as Accept or Accept-Language, ordered by decreasing quality,
+//values of equal quality keep their order. The values of zero or invalid quality are left out
+func Parse(header string) []Preference {
+	var preferences []Preference
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.TrimSpace(fields[0])
+		if value == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil {
+				parsed = 0
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			preferences = append(preferences, Preference{Value: value, Quality: quality})
+		}
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool { return preferences[i].Quality > preferences[j].Quality })
+	return preferences
+}
+
+//Quality returns the quality the header gives to the value, compared case insensitively, zero if the value is not
+//listed. Wildcards are not expanded
+func Quality(preferences []Preference, value string) float64 {
+	for _, preference := range preferences {
+		if strings.EqualFold(preference.Value, value) {
+			return preference.Quality
+		}
+	}
+	return 0
+}
@@ -0,0 +1,23 @@
+package accept
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	assert.EqualValues(t, []Preference{
+		{Value: "application/problem+json", Quality: 1},
+		{Value: "application/json", Quality: 0.9},
+		{Value: "*/*", Quality: 0.1},
+	}, Parse("application/json;q=0.9, */*;q=0.1, application/problem+json, text/html;q=0, text/plain;q=oops"))
+	assert.EqualValues(t, []Preference{{Value: "application/json", Quality: 1}}, Parse("application/json; charset=utf-8"))
+	assert.Empty(t, Parse(""))
+	assert.Empty(t, Parse(" , ;q=1"))
+}
+
+func TestQuality(t *testing.T) {
+	preferences := Parse("Application/Problem+JSON;q=0.5, */*")
+	assert.EqualValues(t, 0.5, Quality(preferences, "application/problem+json"))
+	assert.EqualValues(t, 0, Quality(preferences, "application/json"))
+}
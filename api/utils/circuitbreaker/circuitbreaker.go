@@ -0,0 +1,124 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+//State of a circuit breaker, its value is exposed as is through the metrics
+type State int
+
+const (
+	//Closed lets every call through
+	Closed State = iota
+	//Open rejects every call until the cool-down elapsed
+	Open
+	//HalfOpen lets a single call through, its outcome closes or opens the breaker again
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+//Config defines how many consecutive failures open the breaker and how long it stays open before letting a trial
+//call through
+type Config struct {
+	FailureThreshold int
+	CoolDown         time.Duration
+}
+
+//Breaker stops calling an external api which keeps failing, so that the requests fail fast instead of waiting on it.
+//Every call allowed by Allow must be followed by Success, Failure or Release
+type Breaker struct {
+	mu       sync.Mutex
+	config   Config
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+func New(config Config) *Breaker {
+	return &Breaker{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+//Allow reports whether a call can be made, when it cannot it returns the time left before the breaker lets a trial
+//call through
+func (b *Breaker) Allow() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case Open:
+		return b.openedAt.Add(b.config.CoolDown).Sub(b.now()), false
+	case HalfOpen:
+		if b.probing {
+			//the outcome of the trial call is not known yet, hence the wait is at most a cool-down
+			return b.config.CoolDown, false
+		}
+		b.state = HalfOpen
+		b.probing = true
+	}
+	return 0, true
+}
+
+//Success records a call which proved the external api healthy, closing the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = Closed
+	b.failures = 0
+	b.probing = false
+}
+
+//Failure records a failed call, the breaker opens once the failure threshold is reached or when the trial call failed
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = Open
+		b.openedAt = b.now()
+		b.failures = 0
+	}
+	b.probing = false
+}
+
+//Release records a call whose outcome says nothing about the health of the external api, e.g. cancelled by the caller
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+//State returns the current state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.currentState()
+}
+
+//currentState reports an open breaker whose cool-down elapsed as half open, must be called holding the lock
+func (b *Breaker) currentState() State {
+	if b.state == Open && !b.now().Before(b.openedAt.Add(b.config.CoolDown)) {
+		return HalfOpen
+	}
+	return b.state
+}
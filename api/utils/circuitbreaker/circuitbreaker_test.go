@@ -0,0 +1,95 @@
+package circuitbreaker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestBreaker() (*Breaker, *time.Time) {
+	now := time.Date(2020, time.October, 10, 12, 0, 0, 0, time.UTC)
+	b := New(Config{FailureThreshold: 3, CoolDown: 30 * time.Second})
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker()
+
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	assert.EqualValues(t, Closed, b.State())
+	_, ok := b.Allow()
+	assert.True(t, ok)
+
+	b.Failure()
+	assert.EqualValues(t, Open, b.State())
+	wait, ok := b.Allow()
+	assert.False(t, ok)
+	assert.EqualValues(t, 30*time.Second, wait)
+}
+
+func TestBreakerHalfOpensAfterCoolDown(t *testing.T) {
+	b, now := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+
+	*now = now.Add(20 * time.Second)
+	wait, ok := b.Allow()
+	assert.False(t, ok)
+	assert.EqualValues(t, 10*time.Second, wait)
+
+	*now = now.Add(10 * time.Second)
+	assert.EqualValues(t, HalfOpen, b.State())
+	_, ok = b.Allow()
+	assert.True(t, ok)
+	//a single trial call is let through
+	_, ok = b.Allow()
+	assert.False(t, ok)
+
+	b.Success()
+	assert.EqualValues(t, Closed, b.State())
+	_, ok = b.Allow()
+	assert.True(t, ok)
+}
+
+func TestBreakerReopensWhenTrialCallFails(t *testing.T) {
+	b, now := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+	*now = now.Add(30 * time.Second)
+
+	_, ok := b.Allow()
+	assert.True(t, ok)
+	b.Failure()
+	assert.EqualValues(t, Open, b.State())
+	wait, ok := b.Allow()
+	assert.False(t, ok)
+	assert.EqualValues(t, 30*time.Second, wait)
+}
+
+func TestBreakerRelease(t *testing.T) {
+	b, now := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+	*now = now.Add(30 * time.Second)
+
+	_, ok := b.Allow()
+	assert.True(t, ok)
+	b.Release()
+	assert.EqualValues(t, HalfOpen, b.State())
+	_, ok = b.Allow()
+	assert.True(t, ok)
+}
+
+func TestStateString(t *testing.T) {
+	assert.EqualValues(t, "closed", Closed.String())
+	assert.EqualValues(t, "open", Open.String())
+	assert.EqualValues(t, "half_open", HalfOpen.String())
+}
@@ -0,0 +1,105 @@
+package flavortext
+
+import (
+	"strings"
+	"unicode"
+)
+
+//minShoutingLength is the length from which a word written in capitals is taken for a shouted name rather than an
+//acronym such as HP or DNA
+const minShoutingLength = 4
+
+//layout undoes the layout of the PokeAPI descriptions, which are printed as they were on the game screens: soft hyphens
+//break words across lines and form feeds break pages. Only the words broken by a soft hyphen are joined, a hard hyphen
+//ending a line is kept as is since the games use it both to break words, e.g. sea-weed, and to write compound words,
+//which cannot be told apart without a dictionary. Typographic quotes are straightened so that the translators
+//recognize the words they quote
+var layout = strings.NewReplacer(
+	"\u00ad\r\n", "",
+	"\u00ad\n", "",
+	"\u00ad\f", "",
+	"\u00ad", "",
+	" -\n", " - ",
+	" -\f", " - ",
+	"-\n", "-",
+	"-\f", "-",
+	"\f", " ",
+	"’", "'",
+	"‘", "'",
+	"“", "\"",
+	"”", "\"",
+)
+
+//Normalize turns a PokeAPI description of the named pokemon into a plain sentence: line breaks are undone, whitespaces
+//collapsed and the names written in capitals, e.g. CHARIZARD or POKéMON, are capitalized
+func Normalize(text string, name string) string {
+	text = layout.Replace(text)
+	text = strings.Join(strings.Fields(text), " ")
+	return capitalizeShouting(text, name)
+}
+
+//Unquote removes the quotes the text sent to FunTranslations is wrapped in from its translation, the quotes within the
+//text are kept
+func Unquote(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && strings.HasPrefix(text, "\"") && strings.HasSuffix(text, "\"") {
+		return strings.TrimSpace(text[1 : len(text)-1])
+	}
+	return text
+}
+
+//capitalizeShouting capitalizes the words written in capitals, the words of the pokemon name whatever their length and
+//any other word long enough not to be an acronym
+func capitalizeShouting(text string, name string) string {
+	names := make(map[string]bool)
+	for _, part := range strings.FieldsFunc(strings.ToLower(name), isNotLetter) {
+		names[part] = true
+	}
+
+	var normalized strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if isNotLetter(runes[i]) {
+			normalized.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && !isNotLetter(runes[end]) {
+			end++
+		}
+		word := runes[i:end]
+		if isShouting(word) && (names[strings.ToLower(string(word))] || len(word) >= minShoutingLength) {
+			word = capitalize(word)
+		}
+		normalized.WriteString(string(word))
+		i = end
+	}
+	return normalized.String()
+}
+
+func isNotLetter(r rune) bool {
+	return !unicode.IsLetter(r)
+}
+
+//isShouting tells whether the word is written in capitals, the games could not print a capital É hence POKéMON is
+//written in capitals too, and the plural of a name ends with a small s, e.g. MAGNEMITEs
+func isShouting(word []rune) bool {
+	upper := 0
+	for i, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case r != 'é' && (r != 's' || i != len(word)-1):
+			return false
+		}
+	}
+	return upper >= 2
+}
+
+func capitalize(word []rune) []rune {
+	capitalized := []rune(strings.ToLower(string(word)))
+	capitalized[0] = unicode.ToUpper(capitalized[0])
+	return capitalized
+}
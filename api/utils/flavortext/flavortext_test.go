@@ -0,0 +1,92 @@
+package flavortext
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		name       string
+		text       string
+		normalized string
+	}{
+		{
+			name:       "bulbasaur",
+			text:       "A strange seed was\nplanted on its\nback at birth.\fThe plant sprouts\nand grows with\nthis POKéMON.",
+			normalized: "A strange seed was planted on its back at birth. The plant sprouts and grows with this Pokémon.",
+		},
+		{
+			name:       "charizard",
+			text:       "Spits fire that\nis hot enough to\nmelt boulders.\fKnown to cause\nforest fires\nunintentionally.",
+			normalized: "Spits fire that is hot enough to melt boulders. Known to cause forest fires unintentionally.",
+		},
+		{
+			name:       "pikachu",
+			text:       "Whenever PIKACHU comes across\nsomething new, it blasts it with a jolt of\nelectricity. If you come across a blackened\nberry, it’s evidence that this POKéMON\nmistook the intensity of its charge.",
+			normalized: "Whenever Pikachu comes across something new, it blasts it with a jolt of electricity. If you come across a blackened berry, it's evidence that this Pokémon mistook the intensity of its charge.",
+		},
+		{
+			name:       "mew",
+			text:       "A MEW is said to possess the genes of all\nPOKéMON. It is capable of making itself\ninvisible at will, so it entirely avoids\nnotice even if it approaches people.",
+			normalized: "A Mew is said to possess the genes of all Pokémon. It is capable of making itself invisible at will, so it entirely avoids notice even if it approaches people.",
+		},
+		{
+			name:       "mr-mime",
+			text:       "MR. MIME is a master of pantomime. Its\ngestures and motions convince watchers\nthat something unseeable actually exists.",
+			normalized: "Mr. Mime is a master of pantomime. Its gestures and motions convince watchers that something unseeable actually exists.",
+		},
+		{
+			name:       "chansey",
+			text:       "Its egg restores HP and\nis packed with DNA-rich\nnutrients. It is said to be\fvery deli\u00ad\ncious.",
+			normalized: "Its egg restores HP and is packed with DNA-rich nutrients. It is said to be very delicious.",
+		},
+		{
+			name:       "porygon",
+			text:       "A POKéMON that\nconsists entirely\nof programming\fcode. Capable of\nmoving freely in\ncyberspace.",
+			normalized: "A Pokémon that consists entirely of programming code. Capable of moving freely in cyberspace.",
+		},
+		{
+			//the word broken by a hard hyphen stays broken, see layout
+			name:       "tangela",
+			text:       "The whole body is\nswathed with wide\nvines that are\fsimilar to sea-\nweed. Its vines\nshake as it walks.",
+			normalized: "The whole body is swathed with wide vines that are similar to sea-weed. Its vines shake as it walks.",
+		},
+		{
+			name:       "magneton",
+			text:       "Formed by several\nMAGNEMITEs linked\ntogether. They\ffrequently appear\nwhen sunspots\nflare up.",
+			normalized: "Formed by several Magnemites linked together. They frequently appear when sunspots flare up.",
+		},
+		{
+			name:       "jynx",
+			text:       "It seductively\nwiggles its hips\nas it walks. It\fcan cause people\nto dance in\nunison with it.",
+			normalized: "It seductively wiggles its hips as it walks. It can cause people to dance in unison with it.",
+		},
+		{
+			name:       "missingno",
+			text:       "\n\f ",
+			normalized: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.EqualValues(t, testCase.normalized, Normalize(testCase.text, testCase.name), testCase.name)
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	testCases := []struct {
+		text     string
+		unquoted string
+	}{
+		{text: `"Spits fire."`, unquoted: "Spits fire."},
+		{text: ` "It speaks in a "language" of its own." `, unquoted: `It speaks in a "language" of its own.`},
+		{text: `Spits fire.`, unquoted: "Spits fire."},
+		{text: `"`, unquoted: `"`},
+		{text: `""`, unquoted: ""},
+	}
+
+	for _, testCase := range testCases {
+		assert.EqualValues(t, testCase.unquoted, Unquote(testCase.text), testCase.text)
+	}
+}
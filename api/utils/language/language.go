@@ -0,0 +1,41 @@
+package language
+
+import (
+	"shakespearing-pokemon/api/utils/accept"
+	"strings"
+)
+
+//ParseAcceptLanguage returns the language tags of an Accept-Language header ordered by decreasing quality, tags of
+//equal quality keep their order. The wildcard and the tags of zero or invalid quality are left out
+func ParseAcceptLanguage(header string) []string {
+	parsed := accept.Parse(header)
+	preferences := make([]string, 0, len(parsed))
+	for _, preference := range parsed {
+		if preference.Value != "*" {
+			preferences = append(preferences, preference.Value)
+		}
+	}
+	return preferences
+}
+
+//Match returns the first available language matching a preference. A preference matches the language equal to it or,
+//failing that, a language sharing its primary subtag, e.g. fr-CH matches fr and zh-CN matches zh-Hans
+func Match(preferences []string, available []string) (string, bool) {
+	for _, preference := range preferences {
+		for _, language := range available {
+			if strings.EqualFold(preference, language) {
+				return language, true
+			}
+		}
+		for _, language := range available {
+			if strings.EqualFold(primarySubtag(preference), primarySubtag(language)) {
+				return language, true
+			}
+		}
+	}
+	return "", false
+}
+
+func primarySubtag(tag string) string {
+	return strings.SplitN(tag, "-", 2)[0]
+}
@@ -0,0 +1,36 @@
+package language
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.EqualValues(t, []string{"fr-CH", "fr", "en", "de"}, ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"))
+	assert.EqualValues(t, []string{"de", "ja", "en"}, ParseAcceptLanguage("en;q=0.5,de,it;q=0,ja,es;q=oops"))
+	assert.EqualValues(t, []string{}, ParseAcceptLanguage(""))
+}
+
+func TestMatch(t *testing.T) {
+	available := []string{"ja-Hrkt", "ko", "zh-Hant", "fr", "de", "en", "zh-Hans"}
+
+	testCases := []struct {
+		preferences []string
+		language    string
+		found       bool
+	}{
+		{preferences: []string{"FR"}, language: "fr", found: true},
+		{preferences: []string{"fr-CH"}, language: "fr", found: true},
+		{preferences: []string{"zh-Hans"}, language: "zh-Hans", found: true},
+		{preferences: []string{"zh-CN"}, language: "zh-Hant", found: true},
+		{preferences: []string{"pt", "de", "en"}, language: "de", found: true},
+		{preferences: []string{"pt"}, found: false},
+		{preferences: nil, found: false},
+	}
+
+	for _, testCase := range testCases {
+		language, found := Match(testCase.preferences, available)
+		assert.EqualValues(t, testCase.found, found, testCase.preferences)
+		assert.EqualValues(t, testCase.language, language, testCase.preferences)
+	}
+}
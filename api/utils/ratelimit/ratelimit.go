@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	//ErrLimitExceeded is returned when no token is available within the time the caller accepts to wait
+	ErrLimitExceeded = errors.New("rate limit exceeded")
+)
+
+//Config allows Limit calls per Window, the tokens are refilled continuously so that a spent budget is not restored
+//all at once
+type Config struct {
+	Limit  int
+	Window time.Duration
+}
+
+//Status describes the budget of a limiter
+type Status struct {
+	Limit     int
+	Remaining int
+	//ResetAt is the time the whole budget is available again
+	ResetAt time.Time
+}
+
+//Limiter is a token bucket holding up to Limit tokens, every call takes one token
+type Limiter struct {
+	mu        sync.Mutex
+	config    Config
+	tokens    float64
+	updatedAt time.Time
+	now       func() time.Time
+}
+
+//New returns a limiter whose whole budget is available
+func New(config Config) *Limiter {
+	l := &Limiter{now: time.Now}
+	l.Reset(config)
+	return l
+}
+
+//Reset applies config and restores the whole budget
+func (l *Limiter) Reset(config Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.config = config
+	l.tokens = float64(config.Limit)
+	l.updatedAt = l.now()
+}
+
+//Allow takes a token if one is available, otherwise it returns the time left before the next token
+func (l *Limiter) Allow() (time.Duration, bool) {
+	return l.AllowN(1)
+}
+
+//AllowN takes n tokens if they are all available, otherwise it takes none and returns the time left before they are
+func (l *Limiter) AllowN(n int) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	if l.tokens >= float64(n) {
+		l.tokens -= float64(n)
+		return 0, true
+	}
+	return l.untilTokens(float64(n)), false
+}
+
+//Wait takes a token, waiting for it up to maxWait. When the token would come later than maxWait or than the deadline
+//of ctx, ErrLimitExceeded is returned right away together with the time left before the next token
+func (l *Limiter) Wait(ctx context.Context, maxWait time.Duration) (time.Duration, error) {
+	l.mu.Lock()
+	l.refill()
+	if l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		return 0, nil
+	}
+
+	wait := l.untilTokens(1)
+	deadline, ok := ctx.Deadline()
+	if wait > maxWait || (ok && l.now().Add(wait).After(deadline)) {
+		l.mu.Unlock()
+		return wait, ErrLimitExceeded
+	}
+	//the token is reserved now so that the callers waiting concurrently queue up behind each other
+	l.tokens--
+	l.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return 0, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+//Observe lowers the budget to the calls the limited api reports as remaining, so that calls made by other clients
+//sharing the same quota are accounted for
+func (l *Limiter) Observe(remaining int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	if float64(remaining) < l.tokens {
+		l.tokens = float64(remaining)
+	}
+}
+
+//UntilNext returns the time left before a token is available, zero if one is available now
+func (l *Limiter) UntilNext() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	return l.untilTokens(1)
+}
+
+//Status returns the remaining budget and the time it is whole again
+func (l *Limiter) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	remaining := int(math.Floor(l.tokens))
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Status{
+		Limit:     l.config.Limit,
+		Remaining: remaining,
+		ResetAt:   l.now().Add(l.untilTokens(float64(l.config.Limit))),
+	}
+}
+
+//refill adds the tokens earned since the last update, must be called holding the lock
+func (l *Limiter) refill() {
+	now := l.now()
+	if l.config.Window > 0 {
+		earned := float64(now.Sub(l.updatedAt)) / float64(l.config.Window) * float64(l.config.Limit)
+		l.tokens = math.Min(float64(l.config.Limit), l.tokens+earned)
+	}
+	l.updatedAt = now
+}
+
+//untilTokens returns the time left before the bucket holds the given number of tokens, must be called holding the
+//lock after refill
+func (l *Limiter) untilTokens(tokens float64) time.Duration {
+	missing := tokens - l.tokens
+	if missing <= 0 || l.config.Limit <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(missing / float64(l.config.Limit) * float64(l.config.Window)))
+}
+
+type chargeKey struct{}
+
+//WithCharge returns a copy of ctx carrying the function charging the rate limit of the request with n more calls, so
+//that a request doing the work of several calls can be charged once its size is known
+func WithCharge(ctx context.Context, charge func(n int) (time.Duration, bool)) context.Context {
+	return context.WithValue(ctx, chargeKey{}, charge)
+}
+
+//Charge charges the rate limit carried by ctx with n more calls, as AllowN. A ctx carrying no rate limit allows them
+func Charge(ctx context.Context, n int) (time.Duration, bool) {
+	charge, ok := ctx.Value(chargeKey{}).(func(n int) (time.Duration, bool))
+	if !ok || n <= 0 {
+		return 0, true
+	}
+	return charge(n)
+}
+
+//RetryAfterSeconds rounds a wait, e.g. the one returned by Allow, up to whole seconds, as expected by the Retry-After
+//header
+func RetryAfterSeconds(wait time.Duration) int {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
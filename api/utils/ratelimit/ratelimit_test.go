@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(config Config) (*Limiter, *time.Time) {
+	now := time.Date(2020, time.October, 10, 12, 0, 0, 0, time.UTC)
+	l := &Limiter{now: func() time.Time { return now }}
+	l.Reset(config)
+	return l, &now
+}
+
+func TestAllow(t *testing.T) {
+	l, now := newTestLimiter(Config{Limit: 5, Window: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		_, ok := l.Allow()
+		assert.True(t, ok)
+	}
+	wait, ok := l.Allow()
+	assert.False(t, ok)
+	assert.EqualValues(t, 12*time.Minute, wait)
+
+	//a token is earned every 12 minutes
+	*now = now.Add(12 * time.Minute)
+	_, ok = l.Allow()
+	assert.True(t, ok)
+	_, ok = l.Allow()
+	assert.False(t, ok)
+}
+
+func TestAllowN(t *testing.T) {
+	l, now := newTestLimiter(Config{Limit: 5, Window: time.Hour})
+
+	_, ok := l.AllowN(3)
+	assert.True(t, ok)
+	//no token is taken when some are missing
+	wait, ok := l.AllowN(3)
+	assert.False(t, ok)
+	assert.EqualValues(t, 12*time.Minute, wait)
+	assert.EqualValues(t, 2, l.Status().Remaining)
+
+	*now = now.Add(12 * time.Minute)
+	_, ok = l.AllowN(3)
+	assert.True(t, ok)
+}
+
+func TestCharge(t *testing.T) {
+	_, ok := Charge(context.Background(), 3)
+	assert.True(t, ok)
+
+	l, _ := newTestLimiter(Config{Limit: 5, Window: time.Hour})
+	ctx := WithCharge(context.Background(), l.AllowN)
+	_, ok = Charge(ctx, 4)
+	assert.True(t, ok)
+	_, ok = Charge(ctx, 0)
+	assert.True(t, ok)
+	wait, ok := Charge(ctx, 2)
+	assert.False(t, ok)
+	assert.EqualValues(t, 12*time.Minute, wait)
+}
+
+func TestStatus(t *testing.T) {
+	l, now := newTestLimiter(Config{Limit: 5, Window: time.Hour})
+	assert.EqualValues(t, Status{Limit: 5, Remaining: 5, ResetAt: *now}, l.Status())
+
+	l.Allow()
+	l.Allow()
+	assert.EqualValues(t, Status{Limit: 5, Remaining: 3, ResetAt: now.Add(24 * time.Minute)}, l.Status())
+
+	*now = now.Add(6 * time.Minute)
+	assert.EqualValues(t, Status{Limit: 5, Remaining: 3, ResetAt: now.Add(18 * time.Minute)}, l.Status())
+}
+
+func TestObserve(t *testing.T) {
+	l, _ := newTestLimiter(Config{Limit: 5, Window: time.Hour})
+
+	l.Observe(2)
+	assert.EqualValues(t, 2, l.Status().Remaining)
+	//the remaining calls reported by the api never raise the budget
+	l.Observe(4)
+	assert.EqualValues(t, 2, l.Status().Remaining)
+}
+
+func TestUntilNext(t *testing.T) {
+	l, now := newTestLimiter(Config{Limit: 5, Window: time.Hour})
+	assert.EqualValues(t, 0, l.UntilNext())
+
+	l.Observe(0)
+	assert.EqualValues(t, 12*time.Minute, l.UntilNext())
+
+	*now = now.Add(5 * time.Minute)
+	assert.EqualValues(t, 7*time.Minute, l.UntilNext())
+}
+
+func TestWaitRejects(t *testing.T) {
+	l, _ := newTestLimiter(Config{Limit: 1, Window: time.Hour})
+
+	wait, err := l.Wait(context.Background(), time.Second)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, wait)
+
+	wait, err = l.Wait(context.Background(), time.Second)
+	assert.EqualValues(t, ErrLimitExceeded, err)
+	assert.EqualValues(t, time.Hour, wait)
+}
+
+func TestWaitQueues(t *testing.T) {
+	l := New(Config{Limit: 1, Window: 20 * time.Millisecond})
+	l.Allow()
+
+	start := time.Now()
+	_, err := l.Wait(context.Background(), time.Second)
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+}
+
+func TestWaitBeyondDeadline(t *testing.T) {
+	l := New(Config{Limit: 1, Window: time.Minute})
+	l.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.Wait(ctx, time.Hour)
+	assert.EqualValues(t, ErrLimitExceeded, err)
+}
+
+func TestWaitCancelledGivesTokenBack(t *testing.T) {
+	l, _ := newTestLimiter(Config{Limit: 1, Window: time.Hour})
+	l.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := l.Wait(ctx, 2*time.Hour)
+	assert.EqualValues(t, context.Canceled, err)
+	_, err = l.Wait(context.Background(), time.Second)
+	assert.EqualValues(t, ErrLimitExceeded, err)
+	assert.EqualValues(t, 0, l.Status().Remaining)
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.EqualValues(t, 1, RetryAfterSeconds(0))
+	assert.EqualValues(t, 1, RetryAfterSeconds(300*time.Millisecond))
+	assert.EqualValues(t, 30, RetryAfterSeconds(30*time.Second))
+	assert.EqualValues(t, 31, RetryAfterSeconds(30*time.Second+time.Millisecond))
+}
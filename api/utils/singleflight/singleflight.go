@@ -0,0 +1,111 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//Group collapses concurrent calls sharing the same key into a single execution, the zero value is ready to use
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	value    interface{}
+	panicked interface{}
+	dups     int
+	waiters  int
+}
+
+//Do executes fn unless a call with the same key is already in flight, in which case it waits for that call and
+//returns its value. shared reports whether the value was given to more than one caller.
+//fn receives a context carrying the values of ctx which is cancelled only once every caller gave up, so that a caller
+//giving up does not abort the call the other callers are waiting for. A caller whose ctx is done stops waiting and
+//gets ctx.Err()
+func (g *Group) Do(ctx context.Context, key string, fn func(ctx context.Context) interface{}) (value interface{}, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.dups++
+	} else {
+		fnCtx, cancel := context.WithCancel(detached{ctx})
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.execute(fnCtx, key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
+		g.mu.Lock()
+		shared = c.dups > 0
+		g.mu.Unlock()
+		return c.value, shared, nil
+	case <-ctx.Done():
+		g.leave(key, c)
+		return nil, ok, ctx.Err()
+	}
+}
+
+//leave cancels the call once its last caller stopped waiting, later callers start a new call instead of joining it
+func (g *Group) leave(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	c.cancel()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+func (g *Group) execute(ctx context.Context, key string, c *call, fn func(ctx context.Context) interface{}) {
+	//fn runs in its own goroutine, a panic is handed over to the callers instead of crashing the process
+	defer func() {
+		c.panicked = recover()
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		c.cancel()
+		close(c.done)
+	}()
+
+	c.value = fn(ctx)
+}
+
+//detached keeps the values of its parent context but is not cancelled with it and has no deadline
+type detached struct {
+	parent context.Context
+}
+
+func (d detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detached) Err() error {
+	return nil
+}
+
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
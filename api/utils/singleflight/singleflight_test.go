@@ -0,0 +1,150 @@
+package singleflight
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type contextKey struct{}
+
+func TestDo(t *testing.T) {
+	var group Group
+	value, shared, err := group.Do(context.Background(), "charizard", func(ctx context.Context) interface{} {
+		return "Spits fire that is hot enough to melt boulders."
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Spits fire that is hot enough to melt boulders.", value)
+	assert.False(t, shared)
+}
+
+func TestDoCollapsesConcurrentCalls(t *testing.T) {
+	var group Group
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	fn := func(ctx context.Context) interface{} {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return "charizard"
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make(chan interface{}, callers)
+	sharedResults := make(chan bool, callers)
+
+	call := func() {
+		defer wg.Done()
+		value, shared, err := group.Do(context.Background(), "charizard", fn)
+		assert.Nil(t, err)
+		results <- value
+		sharedResults <- shared
+	}
+
+	wg.Add(callers)
+	go call()
+	<-started
+	for i := 1; i < callers; i++ {
+		go call()
+	}
+	//gives the other callers the time to join the call in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+	close(sharedResults)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	for value := range results {
+		assert.EqualValues(t, "charizard", value)
+	}
+	for shared := range sharedResults {
+		assert.True(t, shared)
+	}
+}
+
+func TestDoAfterCallCompleted(t *testing.T) {
+	var group Group
+	calls := 0
+	fn := func(ctx context.Context) interface{} {
+		calls++
+		return calls
+	}
+
+	first, _, _ := group.Do(context.Background(), "pikachu", fn)
+	second, _, _ := group.Do(context.Background(), "pikachu", fn)
+	assert.EqualValues(t, 1, first)
+	assert.EqualValues(t, 2, second)
+}
+
+//Checks that a caller giving up does not cancel the call the other callers are waiting for
+func TestDoCallerCancelled(t *testing.T) {
+	var group Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fnErrors := make(chan error, 1)
+
+	fn := func(ctx context.Context) interface{} {
+		close(started)
+		<-release
+		fnErrors <- ctx.Err()
+		return ctx.Value(contextKey{})
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey{}, "first caller"))
+	firstErrors := make(chan error, 1)
+	go func() {
+		_, _, err := group.Do(ctx, "mew", fn)
+		firstErrors <- err
+	}()
+	<-started
+
+	done := make(chan interface{})
+	go func() {
+		value, shared, err := group.Do(context.Background(), "mew", fn)
+		assert.Nil(t, err)
+		assert.True(t, shared)
+		done <- value
+	}()
+	//gives the second caller the time to join the call in flight
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.EqualValues(t, context.Canceled, <-firstErrors)
+	close(release)
+
+	assert.EqualValues(t, "first caller", <-done)
+	assert.Nil(t, <-fnErrors)
+}
+
+func TestDoAllCallersCancelled(t *testing.T) {
+	var group Group
+	fnErrors := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	value, shared, err := group.Do(ctx, "mewtwo", func(ctx context.Context) interface{} {
+		<-ctx.Done()
+		fnErrors <- ctx.Err()
+		return nil
+	})
+	assert.Nil(t, value)
+	assert.False(t, shared)
+	assert.EqualValues(t, context.Canceled, err)
+	assert.EqualValues(t, context.Canceled, <-fnErrors)
+}
+
+func TestDoPanic(t *testing.T) {
+	var group Group
+	assert.PanicsWithValue(t, "missingno", func() {
+		group.Do(context.Background(), "missingno", func(ctx context.Context) interface{} {
+			panic("missingno")
+		})
+	})
+}
@@ -1,7 +1,21 @@
 package main
 
-import "shakespearing-pokemon/api/app"
+import (
+	"flag"
+	"os"
+	"shakespearing-pokemon/api/app"
+	"shakespearing-pokemon/api/config"
+	"shakespearing-pokemon/api/logger"
+)
 
 func main() {
-	app.RunApp()
+	cfg, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		logger.Fatal("error when loading the configuration", logger.Fields{"error": err})
+	}
+
+	os.Exit(app.RunApp(cfg))
 }